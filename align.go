@@ -0,0 +1,205 @@
+package word2vec
+
+import "fmt"
+
+// Alignment is a type which represents an orthogonal map (a rotation, possibly with
+// reflection) from the vector space of one model to that of another, computed by Align.
+// Orthogonal maps preserve norms and cosine similarities, so aligning a model does not
+// change the similarities between its own words.
+type Alignment struct {
+	dim int
+	w   []float32 // dim×dim matrix (row-major): aligned vectors are v w
+
+	// Pairs is the number of word pairs the alignment was computed from.
+	Pairs int
+}
+
+// Align computes the orthogonal map from the vectors of src to those of dst which best
+// maps the vector of each word in src to the vector of the paired word in dst (the
+// orthogonal Procrustes problem), so that vectors from src can be compared with vectors
+// from dst.  The pairs are a seed dictionary of (src word, dst word) pairs; if pairs is
+// nil then the words which are in both models are used, each paired with itself.  Pairs
+// containing words which are not in the models are ignored.  The vectors of each pair are
+// normalised, so that each pair has equal weight.  Returns an error if the models have
+// different dimensions, or there are no pairs.
+func Align(src, dst *Model, pairs [][2]string) (*Alignment, error) {
+	if src.dim != dst.dim {
+		return nil, fmt.Errorf("cannot align models of dimension %d and %d", src.dim, dst.dim)
+	}
+	if pairs == nil {
+		pairs = sharedWords(src, dst)
+	}
+
+	// Compute src^T dst over the normalised vectors of the pairs.
+	dim := src.dim
+	a := make([]float64, dim*dim)
+	var n int
+	for _, p := range pairs {
+		i, ok := src.vocab.id(p[0])
+		if !ok {
+			continue
+		}
+		j, ok := dst.vocab.id(p[1])
+		if !ok {
+			continue
+		}
+		u, v := src.row(i), dst.row(j)
+		nu, nv := u.Norm(), v.Norm()
+		if nu == 0 || nv == 0 {
+			continue
+		}
+		for x, ux := range u {
+			ux /= nu
+			for y, vy := range v {
+				a[x*dim+y] += float64(ux * vy / nv)
+			}
+		}
+		n++
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("no word pairs in both models to align")
+	}
+
+	// The solution is u v^T, where u s v^T is the singular value decomposition of a.
+	_, v := svd(a, dim, dim)
+	al := &Alignment{dim: dim, w: make([]float32, dim*dim), Pairs: n}
+	for x := 0; x < dim; x++ {
+		for y := 0; y < dim; y++ {
+			var sum float64
+			for k := 0; k < dim; k++ {
+				sum += a[x*dim+k] * v[y*dim+k]
+			}
+			al.w[x*dim+y] = float32(sum)
+		}
+	}
+	return al, nil
+}
+
+// sharedWords returns the words of a which are also in b, each paired with itself.
+func sharedWords(a, b *Model) [][2]string {
+	var pairs [][2]string
+	for i := 0; i < a.vocab.size(); i++ {
+		w := a.vocab.word(i)
+		if _, ok := b.vocab.id(w); ok {
+			pairs = append(pairs, [2]string{w, w})
+		}
+	}
+	return pairs
+}
+
+// Rotate returns the vector v mapped by the alignment.  This can be used to align vectors
+// derived from the source model (such as stored query or document vectors).  Panics if v
+// does not have the dimension of the models.
+func (a *Alignment) Rotate(v Vector) Vector {
+	if len(v) != a.dim {
+		panic(fmt.Sprintf("vector has dimension %d, expected %d", len(v), a.dim))
+	}
+	out := make(Vector, a.dim)
+	a.rotate(out, v)
+	return out
+}
+
+// rotate sets out to v mapped by the alignment.
+func (a *Alignment) rotate(out, v Vector) {
+	for j := range out {
+		out[j] = 0
+	}
+	for i, x := range v {
+		out.Add(x, Vector(a.w[i*a.dim:(i+1)*a.dim]))
+	}
+}
+
+// Apply returns a Model with the vocabulary of m (usually the source model of the
+// alignment), but with its vectors (and subword vectors, see Subwords) mapped by the
+// alignment, stored as float32.  The vectors are copied, so the model can be used after
+// m is closed.  Returns an error if m does not have the dimension of the aligned models.
+func (a *Alignment) Apply(m *Model) (*Model, error) {
+	if m.dim != a.dim {
+		return nil, fmt.Errorf("cannot apply alignment of dimension %d to model of dimension %d", a.dim, m.dim)
+	}
+	size := m.vocab.size()
+	st := &float32Storage{dim: m.dim, vecs: make([]float32, size*m.dim)}
+	parallel(size, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			a.rotate(st.row(i), m.row(i))
+		}
+	})
+	c := m.convert(st)
+
+	if m.sub != nil {
+		sub := *m.sub
+		sub.vecs = make([]float32, len(m.sub.vecs))
+		parallel(len(sub.vecs)/m.dim, func(lo, hi int) {
+			for i := lo; i < hi; i++ {
+				a.rotate(sub.row(i, m.dim), m.sub.row(i, m.dim))
+			}
+		})
+		c.sub = &sub
+	}
+	return c, nil
+}
+
+// crossCoser is a Coser which evaluates expressions using one model, and finds their
+// nearest neighbours in another model with vectors in the same space.
+type crossCoser struct {
+	src, dst *Model
+}
+
+func (c crossCoser) Cos(e, f Expr) (float32, error) {
+	u, err := e.Eval(c.src)
+	if err != nil {
+		return 0, err
+	}
+	v, err := f.Eval(c.dst)
+	if err != nil {
+		return 0, err
+	}
+	return u.Dot(v), nil
+}
+
+func (c crossCoser) Coses(pairs [][2]Expr) ([]float32, error) {
+	out := make([]float32, len(pairs))
+	for i, p := range pairs {
+		s, err := c.Cos(p[0], p[1])
+		if err != nil {
+			return nil, err
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func (c crossCoser) CosN(e Expr, n int) ([]Match, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	v, err := e.Eval(c.src)
+	if err != nil {
+		return nil, err
+	}
+	if c.dst.sim == Cosine {
+		v.Normalise()
+	}
+	return c.dst.cosineN(v, n), nil
+}
+
+// CompareAlignment reports how well the aligned model (see Alignment.Apply) agrees with
+// dst, using up to k words which are in both models, spread evenly through the vocabulary
+// of aligned.  For each word, the n nearest neighbours in dst of its vector in aligned are
+// compared with the n nearest neighbours in dst of its vector in dst (see RankingReport):
+// Recall is the mean neighbour overlap, and Top1 is the fraction of words with the same
+// nearest neighbour.  Returns an error if k is negative.
+func CompareAlignment(aligned, dst *Model, k, n int) (RankingReport, error) {
+	if k < 0 {
+		return RankingReport{}, fmt.Errorf("invalid number of words %d: must not be negative", k)
+	}
+	shared := sharedWords(aligned, dst)
+	if k > len(shared) {
+		k = len(shared)
+	}
+	queries := make([]Expr, k)
+	for i := range queries {
+		queries[i] = Expr{shared[i*len(shared)/k][0]: 1}
+	}
+	return CompareRankings(dst, crossCoser{aligned, dst}, queries, n)
+}
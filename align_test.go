@@ -0,0 +1,179 @@
+package word2vec
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// testRotatedModel returns a model with the first n words of the model data
+// testRandomModelData(t, n+extra, dim, 1) (named with prefix), followed by extra words
+// which are named so as not to be shared with it.  All the vectors are rotated by a
+// random orthogonal matrix.
+func testRotatedModel(t *testing.T, dim, n, extra int, prefix string) *Model {
+	r := rand.New(rand.NewSource(2))
+	q := make([]float64, dim*dim)
+	for i := range q {
+		q[i] = r.NormFloat64()
+	}
+	svd(q, dim, dim)
+
+	var words []string
+	var vecs []Vector
+	testRandomModelData(t, n+extra, dim, 1, func(_ *rand.Rand, v Vector) {
+		i := len(vecs)
+		if i < n {
+			words = append(words, prefix+testWord(i))
+		} else {
+			words = append(words, "extra"+testWord(i))
+		}
+		u := make(Vector, dim)
+		for x := range v {
+			for y := range u {
+				u[y] += v[x] * float32(q[x*dim+y])
+			}
+		}
+		vecs = append(vecs, u)
+	})
+
+	dst, err := FromReader(bytes.NewReader(testModelData(t, words, vecs)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	return dst
+}
+
+func TestAlign(t *testing.T) {
+	src, err := FromReader(bytes.NewReader(testRandomModelData(t, 300, 8, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		pairs  func() [][2]string
+		n      int
+	}{
+		{"shared", "", func() [][2]string { return nil }, 250},
+		{"seed", "x", func() [][2]string {
+			pairs := [][2]string{{"missing", "xaaa"}}
+			src.Range(func(i int, w string, _ Vector) bool {
+				pairs = append(pairs, [2]string{w, "x" + w})
+				return i < 99
+			})
+			return pairs
+		}, 100},
+	}
+
+	for _, tt := range tests {
+		dst := testRotatedModel(t, 8, 250, 20, tt.prefix)
+		a, err := Align(src, dst, tt.pairs())
+		if err != nil {
+			t.Fatalf("[%s] unexpected error from Align: %v", tt.name, err)
+		}
+		if a.Pairs != tt.n {
+			t.Errorf("[%s] Pairs = %d, expected %d", tt.name, a.Pairs, tt.n)
+		}
+
+		aligned, err := a.Apply(src)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error from Apply: %v", tt.name, err)
+		}
+		if aligned.Size() != src.Size() {
+			t.Errorf("[%s] aligned model size %d, expected %d", tt.name, aligned.Size(), src.Size())
+		}
+
+		// Words which were not used to compute the alignment are also aligned.
+		for _, w := range []string{"aaa", "wda"} {
+			u := aligned.Map([]string{w})[w]
+			v := dst.Map([]string{tt.prefix + w})[tt.prefix+w]
+			if s := u.Dot(v); !approxEqual(s, 1) {
+				t.Errorf("[%s] similarity of aligned %q to %q = %v, expected 1", tt.name, w, tt.prefix+w, s)
+			}
+			if r := a.Rotate(src.Map([]string{w})[w]); !approxEqual(r.Dot(u), 1) {
+				t.Errorf("[%s] Rotate(%q) = %v, expected %v", tt.name, w, r, u)
+			}
+		}
+
+		// Similarities within the model are unchanged.
+		for _, p := range [][2]string{{"aaa", "baa"}, {"cba", "uka"}} {
+			s, _ := src.Cos(Expr{p[0]: 1}, Expr{p[1]: 1})
+			u, _ := aligned.Cos(Expr{p[0]: 1}, Expr{p[1]: 1})
+			if !approxEqual(s, u) {
+				t.Errorf("[%s] aligned Cos(%q, %q) = %v, expected %v", tt.name, p[0], p[1], u, s)
+			}
+		}
+
+		if tt.prefix == "" {
+			r, err := CompareAlignment(aligned, dst, 50, 5)
+			if err != nil {
+				t.Fatalf("[%s] unexpected error from CompareAlignment: %v", tt.name, err)
+			}
+			if r.Queries != 50 || r.Recall < 0.999 || r.Top1 < 0.999 {
+				t.Errorf("[%s] CompareAlignment() = %v, expected 50 queries with recall and top-1 1", tt.name, r)
+			}
+			r, err = CompareAlignment(src, dst, 50, 5)
+			if err != nil {
+				t.Fatalf("[%s] unexpected error from CompareAlignment: %v", tt.name, err)
+			}
+			if r.Recall > 0.5 {
+				t.Errorf("[%s] CompareAlignment() of unaligned model = %v, expected recall < 0.5", tt.name, r)
+			}
+		}
+	}
+}
+
+func TestAlignErrors(t *testing.T) {
+	m := testModelData(t, []string{"hello", "world"}, []Vector{{1, 2}, {2, 1}})
+	a, err := FromReader(bytes.NewReader(m))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	b, err := FromReader(bytes.NewReader(testModelData(t, []string{"hello"}, []Vector{{1, 2, 3}})))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	if _, err := Align(a, b, nil); err == nil {
+		t.Errorf("Align() of models with different dimensions returned nil error")
+	}
+	if _, err := Align(a, a, [][2]string{{"hello", "missing"}}); err == nil {
+		t.Errorf("Align() with no pairs in the models returned nil error")
+	}
+	al, err := Align(a, a, nil)
+	if err != nil {
+		t.Fatalf("unexpected error from Align: %v", err)
+	}
+	if _, err := al.Apply(b); err == nil {
+		t.Errorf("Apply() to model with different dimension returned nil error")
+	}
+	if _, err := CompareAlignment(a, a, -1, 1); err == nil {
+		t.Errorf("CompareAlignment() with negative number of words returned nil error")
+	}
+}
+
+func TestAlignSubwords(t *testing.T) {
+	buckets := make([]Vector, 64)
+	r := rand.New(rand.NewSource(1))
+	for i := range buckets {
+		buckets[i] = Vector{float32(r.NormFloat64()), float32(r.NormFloat64())}
+	}
+	data := testFastTextData(t, []string{"hello", "world"}, []Vector{{1, 0}, {0, 1}}, 2, 3, buckets)
+	m, err := FromFastText(bytes.NewReader(data), Subwords())
+	if err != nil {
+		t.Fatalf("unexpected error from FromFastText: %v", err)
+	}
+
+	// Swap the axes.
+	a := &Alignment{dim: 2, w: []float32{0, 1, 1, 0}}
+	aligned, err := a.Apply(m)
+	if err != nil {
+		t.Fatalf("unexpected error from Apply: %v", err)
+	}
+	u := m.Map([]string{"helo"})["helo"]
+	v := aligned.Map([]string{"helo"})["helo"]
+	if v == nil || !approxEqual(v[0], u[1]) || !approxEqual(v[1], u[0]) {
+		t.Errorf("aligned vector for helo = %v, expected %v", v, Vector{u[1], u[0]})
+	}
+}
@@ -0,0 +1,108 @@
+package word2vec
+
+// builder accumulates the entries of a model as they are read from model data, applying
+// the load options.
+type builder struct {
+	o      *options
+	dim    int
+	vocab  *mapVocab
+	vecs   []float32
+	counts []int // number of entries merged into each word
+}
+
+// newBuilder creates a builder for model data with the given dim, allocating space for
+// size entries.
+func newBuilder(o *options, size, dim int) *builder {
+	return &builder{
+		o:      o,
+		dim:    dim,
+		vocab:  newMapVocab(size),
+		vecs:   make([]float32, 0, size*dim),
+		counts: make([]int, 0, size),
+	}
+}
+
+// full reports whether the builder has reached the maximum vocabulary size, in which
+// case no further entries need to be read.
+func (b *builder) full() bool {
+	return b.o.maxSize > 0 && b.vocab.size() >= b.o.maxSize
+}
+
+// add adds the entry for w with vector v (as it appears in the model data).  The vector
+// is copied.  Returns errDuplicateWord if w has already been added, unless duplicates are
+// allowed (see AllowDuplicates and Fold).
+func (b *builder) add(w string, v Vector) error {
+	if b.o.fold != nil {
+		w = b.o.fold(w)
+	}
+	if b.o.keep != nil && !b.o.keep(w) {
+		return nil
+	}
+
+	if i, ok := b.vocab.id(w); ok {
+		if b.o.fold == nil && !b.o.allowDuplicates {
+			return errDuplicateWord
+		}
+		if b.o.merge == MergeAverage {
+			b.row(i).Add(1, v)
+			b.counts[i]++
+		}
+		return nil
+	}
+	if b.full() {
+		return nil
+	}
+
+	b.vocab.add(w)
+	b.vecs = append(b.vecs, v...)
+	b.counts = append(b.counts, 1)
+	return nil
+}
+
+func (b *builder) row(i int) Vector {
+	return Vector(b.vecs[i*b.dim : (i+1)*b.dim])
+}
+
+// model returns the Model built from the entries added to b.  Returns an error if the
+// vectors cannot be post-processed (see AllButTheTop).
+func (b *builder) model() (*Model, error) {
+	norms := make([]float32, b.vocab.size())
+	parallel(len(norms), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			v := b.row(i)
+			if n := b.counts[i]; n > 1 {
+				for j := range v {
+					v[j] /= float32(n)
+				}
+			}
+			norms[i] = v.Norm()
+			if !b.o.raw {
+				v.Normalise()
+			}
+		}
+	})
+
+	m := &Model{
+		dim:   b.dim,
+		vocab: b.vocab,
+		store: &float32Storage{dim: b.dim, vecs: b.vecs},
+		norms: norms,
+		raw:   b.o.raw,
+	}
+	if b.o.postProcess {
+		var err error
+		if m, err = m.AllButTheTop(b.o.abtt); err != nil {
+			return nil, err
+		}
+	}
+
+	switch b.o.storage {
+	case Float32Storage:
+	case PQStorage:
+		m.store = trainPQ(m.store, m.vocab.size(), m.dim, b.o.pq)
+	default:
+		m.store = newStorage(b.o.storage, m.store, m.vocab.size(), m.dim)
+	}
+	m.fp = fingerprint(m)
+	return m, nil
+}
@@ -95,3 +95,11 @@ func (c *cache) CosN(e Expr, n int) ([]Match, error) {
 	c.cosnCache[eh] = result
 	return result, nil
 }
+
+// Info returns the description of the underlying model, if it has one (see Model.Info).
+func (c *cache) Info() (Info, error) {
+	if i, ok := c.Coser.(infoer); ok {
+		return i.Info()
+	}
+	return Info{}, errNoInfo
+}
@@ -2,15 +2,22 @@
 partition is a tool which reads word2vec classes output and allows you to query the data.
 
    $ partition -p /path/to/classes.txt -q something
+
+Classes can instead be computed from a model using k-means, and written out in the same format:
+
+   $ partition -model /path/to/model.bin -k 500 -o /path/to/classes.txt
 */
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"sort"
 
+	"code.sajari.com/word2vec"
 	"code.sajari.com/word2vec/partition"
 )
 
@@ -19,6 +26,8 @@ var query string
 var class int
 var dist bool
 var info bool
+var modelPath, outPath string
+var classes int
 
 func init() {
 	flag.StringVar(&path, "p", "", "`path` to classes text data")
@@ -26,6 +35,9 @@ func init() {
 	flag.IntVar(&class, "c", 0, "`class` to fetch")
 	flag.BoolVar(&dist, "d", false, "show distribution of classes")
 	flag.BoolVar(&info, "i", false, "only show info about the word class and partition")
+	flag.StringVar(&modelPath, "model", "", "`path` to model data to compute classes from using k-means (instead of -p)")
+	flag.IntVar(&classes, "k", 100, "number of `classes` to compute from -model")
+	flag.StringVar(&outPath, "o", "", "`path` to write the classes to")
 }
 
 type classCount struct {
@@ -49,27 +61,28 @@ func (c classCountSlice) Len() int {
 func main() {
 	flag.Parse()
 
-	if path == "" {
-		fmt.Println("must specify -p; see -h for more details")
+	if (path == "") == (modelPath == "") {
+		fmt.Println("must specify one of -p or -model; see -h for more details")
 		os.Exit(1)
 	}
 
-	if query == "" && class == 0 && !dist {
-		fmt.Println("must specify -q, -c or -d; see -h for more details")
+	if query == "" && class == 0 && !dist && outPath == "" {
+		fmt.Println("must specify -q, -c, -d or -o; see -h for more details")
 		os.Exit(1)
 	}
 
-	f, err := os.Open(path)
-	if err != nil {
-		fmt.Printf("error opening classes data file: %v\n", err)
-		os.Exit(1)
+	var p *partition.Partition
+	if modelPath != "" {
+		p = computePartition()
+	} else {
+		p = readPartition()
 	}
-	defer f.Close()
 
-	p, err := partition.NewPartition(f)
-	if err != nil {
-		fmt.Printf("error reading classes data: %v\n", err)
-		os.Exit(1)
+	if outPath != "" {
+		writePartition(p)
+		if query == "" && class == 0 && !dist {
+			return
+		}
 	}
 
 	if dist {
@@ -94,6 +107,7 @@ func main() {
 
 	var c []string
 	var cl int
+	var err error
 	if query != "" {
 		c, err = p.EquivClass(query)
 		if err != nil {
@@ -130,3 +144,55 @@ func main() {
 		fmt.Printf("%#v\n", w)
 	}
 }
+
+func readPartition() *partition.Partition {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("error opening classes data file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	p, err := partition.NewPartition(f)
+	if err != nil {
+		fmt.Printf("error reading classes data: %v\n", err)
+		os.Exit(1)
+	}
+	return p
+}
+
+func computePartition() *partition.Partition {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	m, err := word2vec.OpenContext(ctx, modelPath)
+	if err != nil {
+		fmt.Printf("error reading model data: %v\n", err)
+		os.Exit(1)
+	}
+	defer m.Close()
+
+	p, err := partition.KMeans(ctx, m, partition.KMeansConfig{Classes: classes})
+	if err != nil {
+		fmt.Printf("error computing classes: %v\n", err)
+		os.Exit(1)
+	}
+	return p
+}
+
+func writePartition(p *partition.Partition) {
+	f, err := os.Create(outPath)
+	if err != nil {
+		fmt.Printf("error creating classes data file: %v\n", err)
+		os.Exit(1)
+	}
+	if err := p.Write(f); err != nil {
+		f.Close()
+		fmt.Printf("error writing classes data: %v\n", err)
+		os.Exit(1)
+	}
+	if err := f.Close(); err != nil {
+		fmt.Printf("error closing classes data file: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,13 +1,14 @@
 /*
-wordcalc is a tool which reads word2vec binary models and allows you to do basic calculations
-with lists of query words.  For instance vec(king) - vec(man) + vec(woman) would be equivalent
-to:
+wordcalc is a tool which reads word2vec models (binary, text, fastText or mapped format) and allows you to do
+basic calculations with lists of query words.  For instance vec(king) - vec(man) + vec(woman) would
+be equivalent to:
 
    $ wordcalc -p /path/to/model.bin -a king,woman -s man
 */
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -17,18 +18,24 @@ import (
 	"code.sajari.com/word2vec"
 )
 
-var path string
+var path, phraseSep string
 var addList, subList string
 var multiQuery string
-var verbose bool
+var verbose, info bool
+var dot, subwords, phrases bool
 var n int
 
 func init() {
-	flag.StringVar(&path, "model", "", "`path` to binary model data")
+	flag.StringVar(&path, "model", "", "`path` to model data (binary, text, fastText or mapped format, optionally compressed)")
 	flag.StringVar(&multiQuery, "words", "", "comma separated list of model `words` to query at the same time")
 	flag.StringVar(&addList, "add", "", "comma separated list of model `words` to add to the target vector")
 	flag.StringVar(&subList, "sub", "", "comma separated list of model `words` to subtract from the target vector")
 	flag.BoolVar(&verbose, "v", false, "show verbose output")
+	flag.BoolVar(&info, "info", false, "describe the model (size, dim, fingerprint and manifest)")
+	flag.BoolVar(&dot, "dot", false, "score by dot product of the model vectors rather than cosine similarity")
+	flag.BoolVar(&subwords, "subwords", false, "synthesise vectors for unknown words from subwords (fastText .bin models)")
+	flag.BoolVar(&phrases, "phrases", false, "look up runs of space separated words in -add and -sub as phrases (e.g. \"new york\" as new_york)")
+	flag.StringVar(&phraseSep, "phrase-sep", word2vec.PhraseSeparator, "`separator` joining the words of phrases in the model, with -phrases")
 	flag.IntVar(&n, "n", 10, "show `N` similar matches")
 }
 
@@ -40,22 +47,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	if addList == "" && subList == "" && multiQuery == "" {
+	if addList == "" && subList == "" && multiQuery == "" && !info {
 		fmt.Println("must specify -add, -sub, or -words; see -h for more details")
 		os.Exit(1)
 	}
 
-	f, err := os.Open(path)
+	var opts []word2vec.Option
+	if subwords {
+		opts = append(opts, word2vec.Subwords())
+	}
+	m, err := word2vec.Open(path, opts...)
 	if err != nil {
-		fmt.Printf("error opening binary model data file: %v\n", err)
+		fmt.Printf("error reading model data: %v\n", err)
 		os.Exit(1)
 	}
-	defer f.Close()
+	defer m.Close()
 
-	m, err := word2vec.FromReader(f)
-	if err != nil {
-		fmt.Printf("error reading binary model data: %v\n", err)
-		os.Exit(1)
+	if dot {
+		m = m.WithSimilarity(word2vec.DotProduct)
+	}
+
+	if info {
+		i, _ := m.Info()
+		b, _ := json.MarshalIndent(i, "", "  ")
+		fmt.Println(string(b))
+		if mf := m.Manifest(); mf != nil {
+			if err := mf.Verify(m); err != nil {
+				fmt.Printf("warning: %v\n", err)
+			}
+		}
+		return
 	}
 
 	// TODO(dhowden): Tidy this up, it's rather hacked in here!
@@ -82,11 +103,20 @@ func main() {
 	}
 
 	expr := word2vec.Expr{}
+	add := func(weight float32, list string) {
+		for _, w := range strings.Split(list, ",") {
+			if phrases {
+				word2vec.AddPhrases(expr, m, phraseSep, weight, strings.Fields(w))
+				continue
+			}
+			expr.Add(weight, w)
+		}
+	}
 	if addList != "" {
-		word2vec.Add(expr, 1, strings.Split(addList, ","))
+		add(1, addList)
 	}
 	if subList != "" {
-		word2vec.Add(expr, -1, strings.Split(subList, ","))
+		add(-1, subList)
 	}
 
 	if verbose {
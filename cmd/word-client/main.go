@@ -5,6 +5,7 @@ model.
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -17,7 +18,7 @@ import (
 var addr string
 var addListA, subListA string
 var addListB, subListB string
-var sim bool
+var sim, info bool
 var n int
 
 func init() {
@@ -27,6 +28,7 @@ func init() {
 	flag.StringVar(&addListB, "addB", "", "comma separated list of model `words` to add to the target vector B")
 	flag.StringVar(&subListB, "subB", "", "comma separated list of model `words` to subtract from the target vector B")
 	flag.BoolVar(&sim, "sim", false, "similarity query")
+	flag.BoolVar(&info, "info", false, "describe the model used by the server (size, dim, fingerprint and manifest)")
 	flag.IntVar(&n, "n", 10, "return `N` similar items in similarity query")
 }
 
@@ -57,6 +59,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	if info {
+		i, err := word2vec.Client{Addr: addr}.Info()
+		if err != nil {
+			fmt.Printf("error describing model: %v\n", err)
+			os.Exit(1)
+		}
+		b, _ := json.MarshalIndent(i, "", "  ")
+		fmt.Println(string(b))
+		return
+	}
+
 	exprA, err := makeExpr(addListA, subListA)
 	if err != nil {
 		fmt.Printf("error creating target vector for 'A': %v\n", err)
@@ -0,0 +1,213 @@
+/*
+word-convert is a tool which reads a word2vec model (binary, text, fastText or mapped format) and writes it
+out in the binary, text or mapped format.  For instance, to convert a text model to the binary format:
+
+	$ word-convert -model /path/to/model.txt -out /path/to/model.bin -format binary
+
+Models in the mapped format can be opened without parsing by the other tools, which is much faster
+for large models:
+
+	$ word-convert -model /path/to/model.bin -out /path/to/model.w2vm -format mmap
+
+Mapped models are opened as they are, so -max-words and -lower do not apply to them.
+
+Mapped models can store vectors with reduced precision (float16 or int8), or using product
+quantisation (pq), to save memory.  The -report flag compares the nearest neighbours of a sample
+of words with those of the original model:
+
+	$ word-convert -model /path/to/model.bin -out /path/to/model.w2vm -format mmap -storage int8 -report 1000
+
+The dimension of the vectors can be reduced using PCA, or a Gaussian or sparse random projection.  The
+fraction of the variance of the vectors which is retained is reported:
+
+	$ word-convert -model /path/to/model.bin -out /path/to/small.bin -reduce pca -dim 100 -report 1000
+
+The vectors can be post-processed by subtracting their mean (-center) and removing their top principal
+components (-abtt), which makes them more isotropic.  Isotropy statistics are reported before and after:
+
+	$ word-convert -model /path/to/model.bin -out /path/to/processed.bin -abtt 3
+
+If the model has a manifest (see word2vec.ManifestPath), it is written alongside the output with the
+fingerprint of the converted model.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"code.sajari.com/word2vec"
+)
+
+var path, outPath, format, storage, reduce string
+var maxWords, report, reportN, subspaces, centroids, dim int
+var lower, center bool
+var abtt int
+
+func init() {
+	flag.StringVar(&path, "model", "", "`path` to model data (binary, text, fastText or mapped format, optionally compressed)")
+	flag.StringVar(&outPath, "out", "", "`path` to write the converted model data to")
+	flag.StringVar(&format, "format", "binary", "output `format`: binary, text or mmap")
+	flag.IntVar(&maxWords, "max-words", 0, "only keep the first `N` words of the model (not for mapped models)")
+	flag.BoolVar(&lower, "lower", false, "lower-case the vocabulary, keeping the first vector for each word (not for mapped models)")
+	flag.StringVar(&storage, "storage", "float32", "vector `storage`: float32, float16, int8 or pq (mmap format only)")
+	flag.IntVar(&subspaces, "pq-subspaces", 0, "number of product quantisation sub-spaces, i.e. `bytes` per vector (default dim/4)")
+	flag.IntVar(&centroids, "pq-centroids", 256, "number of product quantisation centroids per sub-space (at most 256)")
+	flag.StringVar(&reduce, "reduce", "", "reduce the dimension of the vectors to -dim using `method`: pca, gaussian or sparse")
+	flag.IntVar(&dim, "dim", 100, "`dimension` of the vectors with -reduce")
+	flag.BoolVar(&center, "center", false, "subtract the mean of the vectors")
+	flag.IntVar(&abtt, "abtt", 0, "subtract the mean of the vectors and remove their top `D` principal components")
+	flag.IntVar(&report, "report", 0, "report the ranking deviation from the original model for a sample of `N` words")
+	flag.IntVar(&reportN, "report-n", 10, "number of nearest neighbours compared by -report")
+}
+
+func main() {
+	flag.Parse()
+
+	if path == "" || outPath == "" {
+		fmt.Println("must specify -model and -out; see -h for more details")
+		os.Exit(1)
+	}
+
+	if format != "binary" && format != "text" && format != "mmap" {
+		fmt.Printf("invalid -format %q: must be binary, text or mmap\n", format)
+		os.Exit(1)
+	}
+
+	var st word2vec.Storage
+	switch storage {
+	case "float32":
+		st = word2vec.Float32Storage
+	case "float16":
+		st = word2vec.Float16Storage
+	case "int8":
+		st = word2vec.Int8Storage
+	case "pq":
+		st = word2vec.PQStorage
+	default:
+		fmt.Printf("invalid -storage %q: must be float32, float16, int8 or pq\n", storage)
+		os.Exit(1)
+	}
+	if st != word2vec.Float32Storage && format != "mmap" {
+		fmt.Println("-storage requires -format mmap")
+		os.Exit(1)
+	}
+
+	if reduce != "" && reduce != "pca" && reduce != "gaussian" && reduce != "sparse" {
+		fmt.Printf("invalid -reduce %q: must be pca, gaussian or sparse\n", reduce)
+		os.Exit(1)
+	}
+
+	// Keep the vectors as they are in the input, rather than normalising them.
+	opts := []word2vec.Option{word2vec.RawVectors()}
+	if maxWords > 0 {
+		opts = append(opts, word2vec.MaxSize(maxWords))
+	}
+	if lower {
+		opts = append(opts, word2vec.FoldCase())
+	}
+
+	m, err := word2vec.Open(path, opts...)
+	if err != nil {
+		fmt.Printf("error reading model data: %v\n", err)
+		os.Exit(1)
+	}
+	defer m.Close()
+
+	if center || abtt > 0 {
+		n := report
+		if n <= 0 {
+			n = 10000
+		}
+		c, err := m.AllButTheTop(abtt)
+		if err != nil {
+			fmt.Printf("error post-processing vectors: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("before post-processing: %v\n", word2vec.MeasureIsotropy(m, n))
+		fmt.Printf("after post-processing: %v\n", word2vec.MeasureIsotropy(c, n))
+		m = c
+	}
+
+	if reduce != "" {
+		var p *word2vec.Projection
+		if reduce == "pca" {
+			p, err = word2vec.PCA(m, word2vec.PCAConfig{Dim: dim})
+		} else {
+			p, err = word2vec.RandomProjection(m, word2vec.RandomProjectionConfig{Dim: dim, Sparse: reduce == "sparse"})
+		}
+		if err != nil {
+			fmt.Printf("error reducing dimension: %v\n", err)
+			os.Exit(1)
+		}
+		c, err := p.Apply(m)
+		if err != nil {
+			fmt.Printf("error reducing dimension: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s to %d dimensions: retained %.4f of the variance\n", reduce, dim, p.Variance)
+		if report > 0 {
+			r, err := word2vec.CompareRankings(m, c, word2vec.SampleQueries(m, report), reportN)
+			if err != nil {
+				fmt.Printf("error comparing rankings: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%d vs %d dimensions: %v\n", dim, m.Dim(), r)
+		}
+		m = c
+	}
+
+	if st != m.Storage() {
+		var c *word2vec.Model
+		if st == word2vec.PQStorage {
+			c = m.ConvertPQ(word2vec.PQConfig{Subspaces: subspaces, Centroids: centroids})
+		} else {
+			c = m.Convert(st)
+		}
+		if report > 0 {
+			r, err := word2vec.CompareRankings(m, c, word2vec.SampleQueries(m, report), reportN)
+			if err != nil {
+				fmt.Printf("error comparing rankings: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%v vs %v: %v\n", st, m.Storage(), r)
+		}
+		m = c
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		fmt.Printf("error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case "binary":
+		err = m.WriteBinary(out)
+	case "text":
+		err = m.WriteText(out)
+	case "mmap":
+		err = m.WriteMmap(out)
+	}
+	if err != nil {
+		out.Close()
+		fmt.Printf("error writing model data: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := out.Close(); err != nil {
+		fmt.Printf("error closing output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Carry the manifest over to the output, recording the new fingerprint.
+	if mf := m.Manifest(); mf != nil {
+		out := *mf
+		out.Fingerprint = m.Fingerprint()
+		if err := out.WriteFile(word2vec.ManifestPath(outPath)); err != nil {
+			fmt.Printf("error writing manifest: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*
+word-index is a tool which builds an HNSW index of the words of a word2vec model, for fast approximate
+nearest neighbour queries, and writes it out so that it can be loaded by word-server (see -index):
+
+	$ word-index -model /path/to/model.bin -out /path/to/model.hnsw -m 16 -ef-construction 200
+
+The -report flag compares the nearest neighbours of a sample of words found using the index with
+those found by comparing every word in the model (recall@N), and the time taken by each:
+
+	$ word-index -model /path/to/model.bin -out /path/to/model.hnsw -report 1000 -ef-search 64
+
+An existing index can be evaluated by passing it with -index rather than -out.
+
+With -lsh, a random-hyperplane LSH index is built instead, with -tables hash tables of -bits bit
+signatures, searching buckets within Hamming distance -radius of each query.  LSH indexes build
+quickly and are not written out, so -lsh is used with -report to choose the settings:
+
+	$ word-index -model /path/to/model.bin -lsh -tables 8 -bits 16 -radius 1 -report 1000
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"code.sajari.com/word2vec"
+)
+
+var path, outPath, indexPath string
+var report, reportN int
+var lsh bool
+var config word2vec.HNSWConfig
+var lshConfig word2vec.LSHConfig
+
+func init() {
+	flag.StringVar(&path, "model", "", "`path` to model data (binary, text, fastText or mapped format, optionally compressed)")
+	flag.StringVar(&outPath, "out", "", "`path` to write the index to")
+	flag.StringVar(&indexPath, "index", "", "`path` to an existing index to evaluate with -report")
+	flag.IntVar(&config.M, "m", 16, "number of `neighbours` of each word on each layer of the graph")
+	flag.IntVar(&config.EfConstruction, "ef-construction", 200, "number of `candidates` considered when adding each word")
+	flag.IntVar(&config.EfSearch, "ef-search", 64, "number of `candidates` considered by each query")
+	flag.IntVar(&config.Workers, "threads", 0, "number of `goroutines` used to build the index (default GOMAXPROCS)")
+	flag.BoolVar(&lsh, "lsh", false, "evaluate a random-hyperplane LSH index rather than building an HNSW index")
+	flag.IntVar(&lshConfig.Tables, "tables", 8, "number of LSH hash `tables`")
+	flag.IntVar(&lshConfig.Bits, "bits", 0, "number of `bits` of LSH signatures (default log2 of the number of words less 3)")
+	flag.IntVar(&lshConfig.Radius, "radius", 1, "Hamming `distance` of the LSH buckets searched")
+	flag.IntVar(&report, "report", 0, "report the recall of the index for a sample of `N` words")
+	flag.IntVar(&reportN, "report-n", 10, "number of nearest neighbours compared by -report")
+}
+
+func main() {
+	flag.Parse()
+
+	if lsh {
+		if path == "" || report <= 0 {
+			fmt.Println("must specify -model and -report with -lsh; see -h for more details")
+			os.Exit(1)
+		}
+	} else if path == "" || (outPath == "") == (indexPath == "") {
+		fmt.Println("must specify -model, and one of -out or -index; see -h for more details")
+		os.Exit(1)
+	}
+
+	m, err := word2vec.Open(path)
+	if err != nil {
+		fmt.Printf("error reading model data: %v\n", err)
+		os.Exit(1)
+	}
+	defer m.Close()
+
+	var c word2vec.Coser
+	if lsh {
+		start := time.Now()
+		l, err := word2vec.NewLSH(m, lshConfig)
+		if err != nil {
+			fmt.Printf("error building index: %v\n", err)
+			os.Exit(1)
+		}
+		log.Printf("Built LSH index of %d words in %v", m.Size(), time.Since(start).Round(time.Millisecond))
+		c = l
+	} else if indexPath != "" {
+		f, err := os.Open(indexPath)
+		if err != nil {
+			fmt.Printf("error opening index: %v\n", err)
+			os.Exit(1)
+		}
+		h, err := word2vec.ReadHNSW(f, m)
+		f.Close()
+		if err != nil {
+			fmt.Printf("error reading index: %v\n", err)
+			os.Exit(1)
+		}
+		h.SetEfSearch(config.EfSearch)
+		c = h
+	} else {
+		log.Printf("Building index of %d words...", m.Size())
+		start := time.Now()
+		h := word2vec.NewHNSW(m, config)
+		log.Printf("Built index in %v", time.Since(start).Round(time.Millisecond))
+
+		out, err := os.Create(outPath)
+		if err != nil {
+			fmt.Printf("error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		if err := h.Write(out); err != nil {
+			out.Close()
+			fmt.Printf("error writing index: %v\n", err)
+			os.Exit(1)
+		}
+		if err := out.Close(); err != nil {
+			fmt.Printf("error closing output file: %v\n", err)
+			os.Exit(1)
+		}
+		c = h
+	}
+
+	if report > 0 {
+		queries := word2vec.SampleQueries(m, report)
+		r, err := word2vec.CompareRankings(m, c, queries, reportN)
+		if err != nil {
+			fmt.Printf("error comparing rankings: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("index vs exact: %v\n", r)
+		fmt.Printf("mean query time: index %v, exact %v\n", queryTime(c, queries), queryTime(m, queries))
+	}
+}
+
+// queryTime returns the mean time taken by c to find the nearest neighbours of the queries.
+func queryTime(c word2vec.Coser, queries []word2vec.Expr) time.Duration {
+	if len(queries) == 0 {
+		return 0
+	}
+	start := time.Now()
+	for _, q := range queries {
+		c.CosN(q, reportN)
+	}
+	return time.Since(start) / time.Duration(len(queries))
+}
@@ -0,0 +1,87 @@
+/*
+word-phrase is a tool which finds phrases (such as "new york") in a tokenised text corpus, and rewrites
+the corpus with the words of each phrase joined into a single token ("new_york"), so that a model
+trained on the output (see word-train) has vectors for phrases.  The flags follow those of the
+original word2phrase tool:
+
+	$ word-phrase -train /path/to/corpus.txt -output /path/to/phrases.txt -threshold 100 -passes 2
+
+Each pass joins pairs of words or phrases found by the previous pass, so more passes find longer
+phrases.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+
+	"code.sajari.com/word2vec/train"
+)
+
+var corpusPath, outPath string
+var config train.PhraseConfig
+
+func init() {
+	flag.StringVar(&corpusPath, "train", "", "`path` to the tokenised text corpus")
+	flag.StringVar(&outPath, "output", "", "`path` to write the rewritten corpus to")
+	flag.IntVar(&config.MinCount, "min-count", 5, "ignore words and bigrams which occur fewer than `N` times")
+	flag.Float64Var(&config.Threshold, "threshold", 100, "minimum `score` of a bigram to form a phrase (higher means fewer phrases)")
+	flag.IntVar(&config.Passes, "passes", 1, "number of `passes` over the corpus")
+	flag.IntVar(&config.Workers, "threads", 0, "number of `goroutines` used to count bigrams (default GOMAXPROCS)")
+}
+
+func main() {
+	flag.Parse()
+
+	if corpusPath == "" || outPath == "" {
+		fmt.Println("must specify -train and -output; see -h for more details")
+		os.Exit(1)
+	}
+
+	f, err := os.Open(corpusPath)
+	if err != nil {
+		fmt.Printf("error opening corpus: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		fmt.Printf("error opening corpus: %v\n", err)
+		os.Exit(1)
+	}
+	corpus := io.NewSectionReader(f, 0, fi.Size())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Println("Learning phrases...")
+	p, err := train.LearnPhrases(ctx, corpus, config)
+	if err != nil {
+		fmt.Printf("error learning phrases: %v\n", err)
+		os.Exit(1)
+	}
+	log.Printf("Found %d phrases", p.Len())
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		fmt.Printf("error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := p.Rewrite(out, io.NewSectionReader(f, 0, fi.Size())); err != nil {
+		out.Close()
+		fmt.Printf("error writing corpus: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := out.Close(); err != nil {
+		fmt.Printf("error closing output file: %v\n", err)
+		os.Exit(1)
+	}
+}
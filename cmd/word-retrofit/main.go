@@ -0,0 +1,148 @@
+/*
+word-retrofit is a tool which retrofits the vectors of a word2vec model to a lexicon of related words
+(such as synonyms), moving the vectors of related words closer to each other while keeping them close
+to their originals, and writes out the new model in the binary, text or mapped format:
+
+	$ word-retrofit -model /path/to/model.bin -lexicon /path/to/synonyms.txt -out /path/to/retrofitted.bin
+
+The lexicon has a line for each pair of related words, with an optional weight:
+
+	car auto
+	car vehicle 0.5
+
+The mean cosine similarity of the related words in the model, and of the retrofitted vectors with
+their originals, are reported.  Vectors are normalised before they are retrofitted, and written out
+normalised, unless -raw is set.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"code.sajari.com/word2vec"
+)
+
+var path, lexiconPath, outPath, format string
+var raw bool
+var alpha, beta float64
+var config word2vec.RetrofitConfig
+
+func init() {
+	flag.StringVar(&path, "model", "", "`path` to model data (binary, text, fastText or mapped format, optionally compressed)")
+	flag.StringVar(&lexiconPath, "lexicon", "", "`path` to the lexicon: a line for each pair of related words, with an optional weight")
+	flag.StringVar(&outPath, "out", "", "`path` to write the retrofitted model data to")
+	flag.StringVar(&format, "format", "binary", "output `format`: binary, text or mmap")
+	flag.BoolVar(&raw, "raw", false, "retrofit and write the vectors as they are in the input, rather than normalised")
+	flag.IntVar(&config.Iterations, "iter", 10, "number of `iterations`")
+	flag.Float64Var(&alpha, "alpha", 1, "`weight` which keeps each vector close to its original")
+	flag.Float64Var(&beta, "beta", 1, "total `weight` which moves each vector towards those of its related words")
+}
+
+func main() {
+	flag.Parse()
+
+	if path == "" || lexiconPath == "" || outPath == "" {
+		fmt.Println("must specify -model, -lexicon and -out; see -h for more details")
+		os.Exit(1)
+	}
+
+	if format != "binary" && format != "text" && format != "mmap" {
+		fmt.Printf("invalid -format %q: must be binary, text or mmap\n", format)
+		os.Exit(1)
+	}
+	config.Alpha, config.Beta = float32(alpha), float32(beta)
+
+	f, err := os.Open(lexiconPath)
+	if err != nil {
+		fmt.Printf("error opening lexicon: %v\n", err)
+		os.Exit(1)
+	}
+	l, err := word2vec.ReadLexicon(f)
+	f.Close()
+	if err != nil {
+		fmt.Printf("error reading lexicon: %v\n", err)
+		os.Exit(1)
+	}
+
+	var opts []word2vec.Option
+	if raw {
+		opts = append(opts, word2vec.RawVectors())
+	}
+	m, err := word2vec.Open(path, opts...)
+	if err != nil {
+		fmt.Printf("error reading model data: %v\n", err)
+		os.Exit(1)
+	}
+	defer m.Close()
+
+	r, err := word2vec.Retrofit(m, l, config)
+	if err != nil {
+		fmt.Printf("error retrofitting vectors: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Compare the related words in the model before and after.
+	var pairs [][2]word2vec.Expr
+	words := make(map[string]bool)
+	for _, e := range l.Edges() {
+		if _, err := m.ID(e[0]); err != nil {
+			continue
+		}
+		if _, err := m.ID(e[1]); err != nil {
+			continue
+		}
+		pairs = append(pairs, [2]word2vec.Expr{{e[0]: 1}, {e[1]: 1}})
+		words[e[0]], words[e[1]] = true, true
+	}
+	before, err := m.Coses(pairs)
+	if err != nil {
+		fmt.Printf("error comparing vectors: %v\n", err)
+		os.Exit(1)
+	}
+	after, err := r.Coses(pairs)
+	if err != nil {
+		fmt.Printf("error comparing vectors: %v\n", err)
+		os.Exit(1)
+	}
+	var sumBefore, sumAfter float64
+	for i := range pairs {
+		sumBefore += float64(before[i])
+		sumAfter += float64(after[i])
+	}
+	n := float64(len(pairs))
+	fmt.Printf("%d related pairs in the model: mean cosine %.4f before, %.4f after\n", len(pairs), sumBefore/n, sumAfter/n)
+
+	var sum float64
+	for w := range words {
+		u, v := m.Map([]string{w})[w], r.Map([]string{w})[w]
+		sum += float64(u.Dot(v) / (u.Norm() * v.Norm()))
+	}
+	fmt.Printf("%d retrofitted words: mean cosine with original %.4f\n", len(words), sum/float64(len(words)))
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		fmt.Printf("error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case "binary":
+		err = r.WriteBinary(out)
+	case "text":
+		err = r.WriteText(out)
+	case "mmap":
+		err = r.WriteMmap(out)
+	}
+	if err != nil {
+		out.Close()
+		fmt.Printf("error writing model data: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := out.Close(); err != nil {
+		fmt.Printf("error closing output file: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,23 +1,37 @@
 /*
 word-server creates an HTTP server which exports endpoints for querying a word2vec model.
+
+Nearest neighbour queries compare every word in the model, unless an HNSW index built by word-index
+is given with -index, in which case they are approximate but much faster for large models:
+
+	$ word-server -model /path/to/model.bin -index /path/to/model.hnsw
 */
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"time"
 
 	"code.sajari.com/word2vec"
 )
 
-var listen, modelPath string
+var listen, modelPath, indexPath string
+var efSearch int
+var dot, subwords bool
 
 func init() {
 	flag.StringVar(&listen, "listen", "localhost:1234", "bind `address` for HTTP server")
-	flag.StringVar(&modelPath, "model", "", "`path` to binary model data")
+	flag.StringVar(&modelPath, "model", "", "`path` to model data (binary, text, fastText or mapped format, optionally compressed)")
+	flag.BoolVar(&dot, "dot", false, "score by dot product of the model vectors rather than cosine similarity")
+	flag.StringVar(&indexPath, "index", "", "`path` to an HNSW index of the model (see word-index) used for nearest neighbour queries")
+	flag.IntVar(&efSearch, "ef-search", 0, "number of `candidates` considered by each query using -index (default that of the index)")
+	flag.BoolVar(&subwords, "subwords", false, "synthesise vectors for unknown words from subwords (fastText .bin models)")
 }
 
 func main() {
@@ -29,23 +43,77 @@ func main() {
 	}
 
 	log.Println("Loading model...")
-	f, err := os.Open(modelPath)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	opts := []word2vec.Option{word2vec.ReportProgress(logProgress())}
+	if subwords {
+		opts = append(opts, word2vec.Subwords())
+	}
+	m, err := word2vec.OpenContext(ctx, modelPath, opts...)
+	stop()
 	if err != nil {
-		fmt.Printf("error opening binary model data file: %v\n", err)
+		fmt.Printf("error reading model data: %v\n", err)
 		os.Exit(1)
 	}
-	defer f.Close()
+	defer m.Close()
 
-	m, err := word2vec.FromReader(f)
-	if err != nil {
-		fmt.Printf("error reading binary model data: %v\n", err)
-		os.Exit(1)
+	if dot {
+		m = m.WithSimilarity(word2vec.DotProduct)
 	}
 
-	ms := word2vec.NewServer(word2vec.NewCache(m))
+	log.Printf("Loaded model: %d words, dim %d, fingerprint %v", m.Size(), m.Dim(), m.Fingerprint())
+	if mf := m.Manifest(); mf != nil {
+		log.Printf("Model manifest: name %q, version %q, corpus %q, created %q, license %q",
+			mf.Name, mf.Version, mf.Corpus, mf.Created, mf.License)
+		if err := mf.Verify(m); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	var c word2vec.Coser = m
+	if indexPath != "" {
+		f, err := os.Open(indexPath)
+		if err != nil {
+			fmt.Printf("error opening index: %v\n", err)
+			os.Exit(1)
+		}
+		h, err := word2vec.ReadHNSW(f, m)
+		f.Close()
+		if err != nil {
+			fmt.Printf("error reading index: %v\n", err)
+			os.Exit(1)
+		}
+		if efSearch > 0 {
+			h.SetEfSearch(efSearch)
+		}
+		log.Printf("Loaded HNSW index from %v", indexPath)
+		c = h
+	}
+
+	ms := word2vec.NewServer(word2vec.NewCache(c))
 
 	log.Printf("Server listening on %v", listen)
 	log.Println("Hit Ctrl-C to quit.")
 
 	log.Fatal(http.ListenAndServe(listen, ms))
 }
+
+// logProgress returns a function which logs the progress of loading the model, at most
+// every few seconds.
+func logProgress() func(word2vec.Progress) {
+	last := time.Now()
+	return func(p word2vec.Progress) {
+		if time.Since(last) < 5*time.Second {
+			return
+		}
+		last = time.Now()
+
+		switch {
+		case p.Size >= 0:
+			log.Printf("Loaded %d/%d entries (%.1f%%)", p.Entries, p.Size, 100*float64(p.Entries)/float64(p.Size))
+		case p.TotalBytes > 0:
+			log.Printf("Loaded %d entries (%.1f%% of model data)", p.Entries, 100*float64(p.Bytes)/float64(p.TotalBytes))
+		default:
+			log.Printf("Loaded %d entries (%d bytes)", p.Entries, p.Bytes)
+		}
+	}
+}
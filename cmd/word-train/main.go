@@ -0,0 +1,231 @@
+/*
+word-train is a tool which trains a word2vec model from a tokenised text corpus (words separated by
+whitespace, one sentence per line), and writes it out in the binary, text or mapped format.  The
+flags follow those of the original word2vec tool.  For instance, to train 200-dimensional CBOW
+vectors using negative sampling:
+
+	$ word-train -train /path/to/corpus.txt -output /path/to/model.bin -size 200 -cbow -negative 5
+
+An existing model can be fine-tuned on a new corpus with -model: its vocabulary is extended with the
+new words of the corpus, and training continues from its vectors, so the output stays in the same
+vector space.  With -freeze the vectors of the existing words are kept fixed:
+
+	$ word-train -model /path/to/model.bin -train /path/to/new.txt -output /path/to/tuned.bin -iter 2 -freeze
+
+Document vectors (doc2vec) are trained with -docs, in which case each line of the corpus is a document
+whose first word is its tag.  The document vectors are written to the -docs path with the tags as
+words, and are in the same space as the word vectors written to -output.  The output weights, which
+are needed with the word vectors to infer vectors for new documents (see train.NewInferrer), are
+written to -weights.  With -tied the word vectors are used as the output weights instead:
+
+	$ word-train -train /path/to/docs.txt -output /path/to/words.bin -docs /path/to/docs.bin -weights /path/to/weights.bin -dbow
+
+Hit Ctrl-C to stop training early; nothing is written.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"code.sajari.com/word2vec"
+	"code.sajari.com/word2vec/train"
+)
+
+var corpusPath, modelPath, outPath, docsPath, weightsPath, format string
+var cbow, hs, dbow, tied bool
+var config train.Config
+
+func init() {
+	flag.StringVar(&corpusPath, "train", "", "`path` to the tokenised text corpus")
+	flag.StringVar(&modelPath, "model", "", "`path` to model data to fine-tune (binary, text, fastText or mapped format, optionally compressed)")
+	flag.BoolVar(&config.Freeze, "freeze", false, "keep the vectors of the words of -model fixed")
+	flag.StringVar(&outPath, "output", "", "`path` to write the model data to")
+	flag.StringVar(&docsPath, "docs", "", "train document vectors and write them to `path` (the first word of each line is its tag)")
+	flag.BoolVar(&dbow, "dbow", false, "use distributed bag-of-words (PV-DBOW) rather than distributed memory (PV-DM) with -docs")
+	flag.StringVar(&weightsPath, "weights", "", "write the output weights to `path` with -docs, for inferring document vectors")
+	flag.BoolVar(&tied, "tied", false, "use the word vectors as the output weights with -docs")
+	flag.StringVar(&format, "format", "binary", "output `format`: binary, text or mmap")
+	flag.BoolVar(&cbow, "cbow", false, "use continuous bag-of-words rather than skip-gram")
+	flag.BoolVar(&hs, "hs", false, "use hierarchical softmax")
+	flag.IntVar(&config.Dim, "size", 0, "`dimension` of the word vectors (default 100, or that of -model)")
+	flag.IntVar(&config.Window, "window", 5, "maximum `distance` between words in a window")
+	flag.IntVar(&config.MinCount, "min-count", 5, "discard words which occur fewer than `N` times")
+	flag.IntVar(&config.Negative, "negative", 0, "number of negative `samples` (default 5, or 0 with -hs)")
+	flag.Float64Var(&config.Sample, "sample", 1e-3, "`threshold` for down-sampling frequent words (negative to disable)")
+	flag.Float64Var(&config.Alpha, "alpha", 0, "initial learning `rate` (default 0.025 for skip-gram, 0.05 for CBOW)")
+	flag.IntVar(&config.Iterations, "iter", 5, "number of training `iterations`")
+	flag.IntVar(&config.Workers, "threads", 0, "number of training `goroutines` (default GOMAXPROCS)")
+	flag.Int64Var(&config.Seed, "seed", 0, "random `seed`")
+}
+
+func main() {
+	flag.Parse()
+
+	if corpusPath == "" || outPath == "" {
+		fmt.Println("must specify -train and -output; see -h for more details")
+		os.Exit(1)
+	}
+
+	if format != "binary" && format != "text" && format != "mmap" {
+		fmt.Printf("invalid -format %q: must be binary, text or mmap\n", format)
+		os.Exit(1)
+	}
+
+	if docsPath != "" && (modelPath != "" || cbow || hs) {
+		fmt.Println("-docs cannot be used with -model, -cbow or -hs")
+		os.Exit(1)
+	}
+	if docsPath == "" && (weightsPath != "" || tied) {
+		fmt.Println("-weights and -tied require -docs")
+		os.Exit(1)
+	}
+
+	if cbow {
+		config.Arch = train.CBOW
+	}
+	config.HierarchicalSoftmax = hs
+	config.Progress = logProgress()
+
+	f, err := os.Open(corpusPath)
+	if err != nil {
+		fmt.Printf("error opening corpus: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		fmt.Printf("error opening corpus: %v\n", err)
+		os.Exit(1)
+	}
+	corpus := io.NewSectionReader(f, 0, fi.Size())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if docsPath != "" {
+		trainDocs(ctx, corpus)
+		return
+	}
+
+	var t *train.Trainer
+	if modelPath != "" {
+		log.Println("Loading model...")
+		m, err := word2vec.OpenContext(ctx, modelPath)
+		if err != nil {
+			fmt.Printf("error reading model data: %v\n", err)
+			os.Exit(1)
+		}
+		log.Println("Building vocabulary...")
+		n := m.Size()
+		t, err = train.FromModel(ctx, m, corpus, config)
+		m.Close()
+		if err != nil {
+			fmt.Printf("error building vocabulary: %v\n", err)
+			os.Exit(1)
+		}
+		log.Printf("Vocabulary: %d words (%d new), %d words in corpus", t.Vocab().Size(), t.Vocab().Size()-n, t.Vocab().Total())
+	} else {
+		log.Println("Building vocabulary...")
+		v, err := train.BuildVocab(ctx, corpus, config.MinCount, config.Workers)
+		if err != nil {
+			fmt.Printf("error building vocabulary: %v\n", err)
+			os.Exit(1)
+		}
+		log.Printf("Vocabulary: %d words, %d words in corpus", v.Size(), v.Total())
+		t = train.New(v, config)
+	}
+
+	log.Printf("Training %v model...", config.Arch)
+	if err := t.Train(ctx, corpus); err != nil {
+		fmt.Printf("error training model: %v\n", err)
+		os.Exit(1)
+	}
+
+	write(outPath, t.WriteBinary, t.Model)
+}
+
+// trainDocs trains document and word vectors on the corpus, and writes them out.
+func trainDocs(ctx context.Context, corpus train.Corpus) {
+	dc := train.DocConfig{Config: config, TrainWords: true, TiedWeights: tied}
+	if dbow {
+		dc.Mode = train.PVDBOW
+	}
+
+	log.Println("Building vocabulary...")
+	t, err := train.NewDocTrainer(ctx, corpus, dc)
+	if err != nil {
+		fmt.Printf("error building vocabulary: %v\n", err)
+		os.Exit(1)
+	}
+	log.Printf("Vocabulary: %d words, %d words in corpus, %d documents", t.Vocab().Size(), t.Vocab().Total(), t.Docs())
+
+	log.Printf("Training %v model...", dc.Mode)
+	if err := t.Train(ctx, corpus); err != nil {
+		fmt.Printf("error training model: %v\n", err)
+		os.Exit(1)
+	}
+
+	write(outPath, t.WriteBinary, t.Model)
+	write(docsPath, t.WriteDocs, t.DocModel)
+	if weightsPath != "" {
+		write(weightsPath, t.WriteWeights, t.WeightsModel)
+	}
+}
+
+// write writes model data to path in the output format, using writeBinary for the binary
+// format, and the model returned by model otherwise.
+func write(path string, writeBinary func(io.Writer) error, model func(...word2vec.Option) (*word2vec.Model, error)) {
+	out, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case "binary":
+		err = writeBinary(out)
+	default:
+		var m *word2vec.Model
+		m, err = model(word2vec.RawVectors())
+		if err != nil {
+			break
+		}
+		if format == "text" {
+			err = m.WriteText(out)
+		} else {
+			err = m.WriteMmap(out)
+		}
+	}
+	if err != nil {
+		out.Close()
+		fmt.Printf("error writing model data: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := out.Close(); err != nil {
+		fmt.Printf("error closing output file: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// logProgress returns a function which logs the progress of training, at most every few
+// seconds.
+func logProgress() func(train.Progress) {
+	last := time.Now()
+	return func(p train.Progress) {
+		if time.Since(last) < 5*time.Second {
+			return
+		}
+		last = time.Now()
+		log.Printf("Iteration %d: trained %d/%d words (%.1f%%), alpha %.6f",
+			p.Iteration+1, p.Words, p.TotalWords, 100*float64(p.Words)/float64(p.TotalWords), p.Alpha)
+	}
+}
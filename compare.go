@@ -0,0 +1,104 @@
+package word2vec
+
+import (
+	"fmt"
+	"math"
+)
+
+// RankingReport is a type which summarises how the nearest neighbours returned by a
+// Coser deviate from those of a reference Coser (see CompareRankings).
+type RankingReport struct {
+	Queries int // number of queries compared
+	N       int // number of neighbours compared for each query
+
+	// Recall is the mean fraction of the reference neighbours which are also returned
+	// (recall@N).
+	Recall float64
+
+	// Top1 is the fraction of queries for which the nearest neighbour is the same.
+	Top1 float64
+
+	// RankDisplacement is the mean absolute difference in rank of the neighbours
+	// returned by both.
+	RankDisplacement float64
+
+	// ScoreError is the mean absolute difference in score of the neighbours returned
+	// by both.
+	ScoreError float64
+}
+
+func (r RankingReport) String() string {
+	return fmt.Sprintf("%d queries: recall@%d %.4f, top-1 %.4f, mean rank displacement %.3f, mean score error %.5f",
+		r.Queries, r.N, r.Recall, r.Top1, r.RankDisplacement, r.ScoreError)
+}
+
+// CompareRankings evaluates each of the queries using ref and c, and reports how the n
+// nearest neighbours returned by c deviate from those returned by ref.  For instance,
+// ref could be a model with float32 vectors and c the same model with reduced-precision
+// storage (see Model.Convert).  Returns an error if a query cannot be evaluated.
+func CompareRankings(ref, c Coser, queries []Expr, n int) (RankingReport, error) {
+	r := RankingReport{Queries: len(queries), N: n}
+	if len(queries) == 0 || n <= 0 {
+		return r, nil
+	}
+
+	var recall, top1, disp, scoreErr float64
+	shared := 0
+	for _, q := range queries {
+		want, err := ref.CosN(q, n)
+		if err != nil {
+			return RankingReport{}, err
+		}
+		got, err := c.CosN(q, n)
+		if err != nil {
+			return RankingReport{}, err
+		}
+
+		ranks := make(map[string]int, len(got))
+		for i, m := range got {
+			ranks[m.Word] = i
+		}
+		found := 0
+		for i, m := range want {
+			j, ok := ranks[m.Word]
+			if !ok {
+				continue
+			}
+			found++
+			disp += math.Abs(float64(i - j))
+			scoreErr += math.Abs(float64(m.Score - got[j].Score))
+		}
+		shared += found
+		if len(want) > 0 {
+			recall += float64(found) / float64(len(want))
+		}
+		if len(want) > 0 && len(got) > 0 && want[0].Word == got[0].Word {
+			top1++
+		}
+	}
+
+	r.Recall = recall / float64(len(queries))
+	r.Top1 = top1 / float64(len(queries))
+	if shared > 0 {
+		r.RankDisplacement = disp / float64(shared)
+		r.ScoreError = scoreErr / float64(shared)
+	}
+	return r, nil
+}
+
+// SampleQueries returns up to k single-word queries spread evenly through the vocabulary
+// of m, for use with CompareRankings.  Returns nil if k <= 0 or m has no words.
+func SampleQueries(m *Model, k int) []Expr {
+	size := m.Size()
+	if k > size {
+		k = size
+	}
+	if k <= 0 {
+		return nil
+	}
+	queries := make([]Expr, k)
+	for i := range queries {
+		queries[i] = Expr{m.vocab.word(i * size / k): 1}
+	}
+	return queries
+}
@@ -0,0 +1,89 @@
+package word2vec
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// compression is a type which represents a compression format supported for model data.
+type compression struct {
+	name  string
+	magic []byte
+
+	// reader returns a reader which decompresses r, and a function which releases any
+	// resources held by the reader.
+	reader func(r io.Reader) (io.Reader, func(), error)
+}
+
+var compressions = []compression{
+	{
+		name:  "gzip",
+		magic: []byte{0x1f, 0x8b},
+		reader: func(r io.Reader) (io.Reader, func(), error) {
+			zr, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			return zr, func() { zr.Close() }, nil
+		},
+	},
+	{
+		name:  "bzip2",
+		magic: []byte("BZh"),
+		reader: func(r io.Reader) (io.Reader, func(), error) {
+			return bzip2.NewReader(r), func() {}, nil
+		},
+	},
+	{
+		name:  "xz",
+		magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		reader: func(r io.Reader) (io.Reader, func(), error) {
+			xr, err := xz.NewReader(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			return xr, func() {}, nil
+		},
+	},
+	{
+		name:  "zstd",
+		magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		reader: func(r io.Reader) (io.Reader, func(), error) {
+			zr, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			return zr, zr.Close, nil
+		},
+	},
+}
+
+// decompress detects whether the data in br is compressed (using its magic bytes) and
+// if so returns a reader of the decompressed data.  Otherwise br is returned.  The
+// returned function releases any resources held by the reader, and must be called when
+// it is no longer needed.
+func decompress(br *bufio.Reader) (*bufio.Reader, func(), error) {
+	for _, c := range compressions {
+		b, err := br.Peek(len(c.magic))
+		if err != nil && err != io.EOF {
+			return nil, nil, err
+		}
+		if !bytes.Equal(b, c.magic) {
+			continue
+		}
+
+		r, done, err := c.reader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s compressed model data: %v", c.name, err)
+		}
+		return bufio.NewReaderSize(r, sniffSize), done, nil
+	}
+	return br, func() {}, nil
+}
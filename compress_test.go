@@ -0,0 +1,82 @@
+package word2vec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// bzip2Model is "2 2\nhello 0 1\nworld 1 0\n" compressed with bzip2 (there is no bzip2
+// writer in the standard library).
+var bzip2Model = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xa0, 0xc2,
+	0xe8, 0xbb, 0x00, 0x00, 0x06, 0xd9, 0x80, 0x00, 0x10, 0x40, 0x00, 0x70,
+	0x00, 0x06, 0x44, 0x90, 0x80, 0x20, 0x00, 0x21, 0xa9, 0x93, 0xd4, 0xf5,
+	0x36, 0x50, 0x40, 0xd0, 0x34, 0x2c, 0x90, 0xf6, 0x8a, 0xd0, 0x4d, 0xdc,
+	0x84, 0x7a, 0x73, 0xe2, 0xee, 0x48, 0xa7, 0x0a, 0x12, 0x14, 0x18, 0x5d,
+	0x17, 0x60,
+}
+
+func compressTestData(t *testing.T, data []byte, w func(io.Writer) (io.WriteCloser, error)) []byte {
+	buf := &bytes.Buffer{}
+	zw, err := w(buf)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("unexpected error compressing data: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected error closing writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadCompressed(t *testing.T) {
+	bin := testModelData(t, []string{"hello", "world"}, []Vector{{0, 1}, {1, 0}})
+	m, err := FromReader(bytes.NewReader(bin))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	mm := &bytes.Buffer{}
+	if err := m.WriteMmap(mm); err != nil {
+		t.Fatalf("unexpected error from WriteMmap: %v", err)
+	}
+
+	gz := func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"gzip binary", compressTestData(t, bin, gz)},
+		{"gzip mmap", compressTestData(t, mm.Bytes(), gz)},
+		{"bzip2 text", bzip2Model},
+		{"xz binary", compressTestData(t, bin, func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) })},
+		{"zstd binary", compressTestData(t, bin, func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) })},
+	}
+
+	expected := map[string]Vector{
+		"hello": Vector{0, 1},
+		"world": Vector{1, 0},
+	}
+
+	for _, tt := range tests {
+		m, err := Load(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("[%s] unexpected error from Load: %v", tt.name, err)
+			continue
+		}
+		if vecs := m.Map([]string{"hello", "world"}); !reflect.DeepEqual(vecs, expected) {
+			t.Errorf("[%s] m.Map() = %v, expected %v", tt.name, vecs, expected)
+		}
+	}
+
+	if _, err := Load(bytes.NewReader(compressTestData(t, bin, gz)[:20])); err == nil {
+		t.Errorf("expected error from Load with truncated gzip data")
+	}
+}
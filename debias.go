@@ -0,0 +1,344 @@
+package word2vec
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// BiasSubspace is a type which represents a subspace of the vectors of a model which
+// captures a bias (such as gender), computed from pairs of words which differ by the bias
+// (see NewBiasSubspace).
+type BiasSubspace struct {
+	basis []Vector // orthonormal
+
+	// Variance is the fraction of the variance of the differences between the words of
+	// each pair which lies in the subspace.
+	Variance float64
+}
+
+// NewBiasSubspace returns the subspace spanned by the first k principal components of the
+// differences between the normalised vectors of the words of each pair (for instance,
+// {"he", "she"}, {"man", "woman"}), as described by Bolukbasi et al. (2016).  With k = 1
+// the subspace is a single bias direction.  Returns an error if a word is not in m, if
+// k is not between 1 and the dimension of m, or if the differences do not span k dimensions.
+func NewBiasSubspace(m *Model, pairs [][2]string, k int) (*BiasSubspace, error) {
+	if k <= 0 || k > m.dim {
+		return nil, fmt.Errorf("invalid number of components %d: must be between 1 and the model dimension %d", k, m.dim)
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no pairs of words given")
+	}
+
+	// The vectors of each pair, centred on their mean, are ±(a - b)/2.
+	gram := make([]float64, m.dim*m.dim)
+	d := make([]float64, m.dim)
+	for _, p := range pairs {
+		var u [2]Vector
+		for j, w := range p {
+			i, ok := m.vocab.id(w)
+			if !ok {
+				return nil, &NotFoundError{w}
+			}
+			u[j] = unit(m.row(i))
+		}
+		for j := range d {
+			d[j] = float64(u[0][j]-u[1][j]) / 2
+		}
+		for x, dx := range d {
+			for y, dy := range d {
+				gram[x*m.dim+y] += 2 * dx * dy
+			}
+		}
+	}
+
+	s, _ := svd(gram, m.dim, m.dim)
+	b := &BiasSubspace{basis: make([]Vector, k)}
+	for c := range b.basis {
+		b.basis[c] = make(Vector, m.dim)
+		for j := range b.basis[c] {
+			b.basis[c][j] = float32(gram[j*m.dim+c])
+		}
+	}
+	var total, retained float64
+	for c, x := range s {
+		total += x
+		if c < k {
+			retained += x
+		}
+	}
+	if total == 0 || s[k-1] <= 1e-12*s[0] {
+		return nil, fmt.Errorf("differences of pairs do not span a %d-dimensional subspace", k)
+	}
+	b.Variance = retained / total
+	return b, nil
+}
+
+// Dim returns the dimension of the subspace.
+func (b *BiasSubspace) Dim() int {
+	return len(b.basis)
+}
+
+// Bias returns the norm of the projection of the normalised vector v onto the subspace,
+// which is between 0 (unbiased) and 1 (v lies in the subspace).  For a subspace of
+// dimension 1 this is the absolute cosine similarity of v and the bias direction.
+// Panics if v does not have the dimension of the model.
+func (b *BiasSubspace) Bias(v Vector) float32 {
+	if len(v) != len(b.basis[0]) {
+		panic(fmt.Sprintf("vector has dimension %d, expected %d", len(v), len(b.basis[0])))
+	}
+	return b.project(unit(v)).Norm()
+}
+
+// project returns the projection of v onto the subspace.
+func (b *BiasSubspace) project(v Vector) Vector {
+	out := make(Vector, len(v))
+	for _, c := range b.basis {
+		out.Add(v.Dot(c), c)
+	}
+	return out
+}
+
+// DebiasConfig is a type which configures debiasing (see BiasSubspace.Debias).  Zero
+// fields take their default values.
+type DebiasConfig struct {
+	// Exclude lists words whose vectors are not neutralised, such as words which are
+	// specific to one side of the bias ("he", "queen").  Words which are not in the model
+	// are ignored.
+	Exclude []string
+
+	// Equalize lists pairs of words (such as {"grandfather", "grandmother"}) whose vectors
+	// are replaced by vectors which differ only in the subspace, so that neutralised
+	// words are equally similar to both.  The words of the pairs are not neutralised.
+	// Pairs with a word which is not in the model are ignored.
+	Equalize [][2]string
+
+	// Strength is the fraction of the component in the subspace which is removed from
+	// the vectors of neutralised words.  Less than 1 only reduces the bias (a simple
+	// form of soft debiasing).  Defaults to 1 (hard debiasing).
+	Strength float32
+}
+
+// Debias returns a Model with the vocabulary of m, whose vectors are debiased with respect
+// to the subspace (Bolukbasi et al., 2016).  The vectors of words which are not excluded
+// (see DebiasConfig) are neutralised, by removing their component in the subspace, and
+// the vectors of the pairs c.Equalize are equalised.  Vectors are normalised before they
+// are debiased.
+//
+// The vectors are stored as float32, and are normalised unless m was loaded with
+// RawVectors (in which case they keep their original norms, scaled by the change in norm
+// of the debiased vectors).  The norms of the new model (see Model.Norm and DotProduct)
+// are scaled in the same way.  The vectors are copied, so the model can be used after m
+// is closed.  Subword vectors (see Subwords) are not kept.  Returns an error if m does
+// not have the dimension of the subspace.
+func (b *BiasSubspace) Debias(m *Model, c DebiasConfig) (*Model, error) {
+	if len(b.basis[0]) != m.dim {
+		return nil, fmt.Errorf("cannot debias model of dimension %d with subspace of vectors of dimension %d", m.dim, len(b.basis[0]))
+	}
+	if c.Strength <= 0 {
+		c.Strength = 1
+	}
+
+	exclude := make(map[int]bool)
+	for _, w := range c.Exclude {
+		if i, ok := m.vocab.id(w); ok {
+			exclude[i] = true
+		}
+	}
+	var pairs [][2]int
+	for _, p := range c.Equalize {
+		i, ok := m.vocab.id(p[0])
+		if !ok {
+			continue
+		}
+		j, ok := m.vocab.id(p[1])
+		if !ok || i == j {
+			continue
+		}
+		pairs = append(pairs, [2]int{i, j})
+		exclude[i], exclude[j] = true, true
+	}
+
+	size := m.vocab.size()
+	st := &float32Storage{dim: m.dim, vecs: make([]float32, size*m.dim)}
+	norms := make([]float32, size)
+	parallel(size, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			v := st.row(i)
+			copy(v, m.row(i))
+			norms[i] = m.norm(i)
+			if exclude[i] {
+				continue
+			}
+			n := v.Norm()
+			if n == 0 {
+				continue
+			}
+			v.Add(-c.Strength, b.project(v))
+			scale := v.Norm() / n
+			norms[i] *= scale
+			if !m.raw && scale != 0 {
+				v.Normalise()
+			}
+		}
+	})
+
+	// Each pair is replaced by the unit vectors nu ± sqrt(1 - |nu|^2) e, where nu is the
+	// component of their mean outside the subspace, and e is a unit vector in the
+	// subspace.
+	for _, p := range pairs {
+		u, v := unit(m.row(p[0])), unit(m.row(p[1]))
+		mu := append(Vector(nil), u...)
+		mu.Add(1, v)
+		for j := range mu {
+			mu[j] /= 2
+		}
+		muB := b.project(mu)
+		nu := append(Vector(nil), mu...)
+		nu.Add(-1, muB)
+		scale := float32(math.Sqrt(math.Max(0, float64(1-nu.Dot(nu)))))
+		for k, w := range [2]Vector{u, v} {
+			e := b.project(w)
+			e.Add(-1, muB)
+			n := e.Norm()
+			if n == 0 {
+				continue
+			}
+			out := st.row(p[k])
+			copy(out, nu)
+			out.Add(scale/n, e)
+			if m.raw {
+				norm := m.row(p[k]).Norm()
+				for j := range out {
+					out[j] *= norm
+				}
+			}
+		}
+	}
+
+	r := *m
+	r.sub = nil
+	r.norms = norms
+	return r.convert(st), nil
+}
+
+// unit returns a normalised copy of v.
+func unit(v Vector) Vector {
+	u := append(Vector(nil), v...)
+	if u.Norm() != 0 {
+		u.Normalise()
+	}
+	return u
+}
+
+// Association is a type which reports the differential association of two sets of
+// target words with two sets of attribute words (see WEAT).
+type Association struct {
+	// Statistic is the sum of s(x, A, B) over the words x of the first target set, less
+	// the sum over the words of the second, where s(w, A, B) is the mean cosine
+	// similarity of w with the words of the first attribute set A, less the mean with the
+	// words of the second B.
+	Statistic float64
+
+	// EffectSize is the difference of the means of s(w, A, B) over the two target sets,
+	// divided by its standard deviation over both (Cohen's d).  Positive values mean the
+	// first target set is more associated with the first attribute set.
+	EffectSize float64
+
+	// PValue is the one-sided p-value of the statistic, estimated as the fraction of
+	// random equal-size partitions of the target words whose statistic is at least as
+	// large.
+	PValue float64
+}
+
+func (a Association) String() string {
+	return fmt.Sprintf("statistic %.4f, effect size %.4f, p-value %.4f", a.Statistic, a.EffectSize, a.PValue)
+}
+
+// weatPermutations is the number of random partitions used to estimate the p-value.
+const weatPermutations = 10000
+
+// WEAT runs the word embedding association test (Caliskan et al., 2017) of the target
+// word sets x and y (such as career and family words) against the attribute word sets
+// a and b (such as male and female words), using the cosine similarities computed by c.
+// Returns an error if a set is empty, or a word is not in the model.
+func WEAT(c Coser, x, y, a, b []string) (Association, error) {
+	if len(x) == 0 || len(y) == 0 || len(a) == 0 || len(b) == 0 {
+		return Association{}, fmt.Errorf("target and attribute word sets must not be empty")
+	}
+
+	targets := append(append([]string(nil), x...), y...)
+	var pairs [][2]Expr
+	for _, w := range targets {
+		for _, attr := range [][]string{a, b} {
+			for _, v := range attr {
+				pairs = append(pairs, [2]Expr{{w: 1}, {v: 1}})
+			}
+		}
+	}
+	cos, err := c.Coses(pairs)
+	if err != nil {
+		return Association{}, err
+	}
+
+	// s(w, A, B) for each target word.
+	s := make([]float64, len(targets))
+	k := 0
+	for i := range targets {
+		for j, attr := range [][]string{a, b} {
+			var sum float64
+			for range attr {
+				sum += float64(cos[k])
+				k++
+			}
+			if j == 0 {
+				s[i] += sum / float64(len(attr))
+			} else {
+				s[i] -= sum / float64(len(attr))
+			}
+		}
+	}
+
+	statistic := func(s []float64) float64 {
+		var sum float64
+		for _, v := range s[:len(x)] {
+			sum += v
+		}
+		for _, v := range s[len(x):] {
+			sum -= v
+		}
+		return sum
+	}
+
+	var r Association
+	r.Statistic = statistic(s)
+	var mean, sq float64
+	for _, v := range s {
+		mean += v / float64(len(s))
+	}
+	for _, v := range s {
+		sq += (v - mean) * (v - mean)
+	}
+	if sd := math.Sqrt(sq / float64(len(s)-1)); sd > 0 {
+		var mx, my float64
+		for _, v := range s[:len(x)] {
+			mx += v / float64(len(x))
+		}
+		for _, v := range s[len(x):] {
+			my += v / float64(len(y))
+		}
+		r.EffectSize = (mx - my) / sd
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	perm := append([]float64(nil), s...)
+	count := 0
+	for i := 0; i < weatPermutations; i++ {
+		rng.Shuffle(len(perm), func(i, j int) { perm[i], perm[j] = perm[j], perm[i] })
+		if statistic(perm) >= r.Statistic {
+			count++
+		}
+	}
+	r.PValue = float64(count) / weatPermutations
+	return r, nil
+}
@@ -0,0 +1,196 @@
+package word2vec
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// testBiasModel returns a model whose first dimension is a bias direction.
+func testBiasModel(t *testing.T, opts ...Option) *Model {
+	words := []string{"he", "she", "man", "woman", "engineer", "doctor", "nurse", "teacher", "grandfather", "grandmother"}
+	vecs := []Vector{
+		{1, 0, 1, 0},
+		{-1, 0, 1, 0},
+		{1, 0, 0, 1},
+		{-1, 0, 0, 1},
+		{0.5, 1, 0, 0},
+		{0.4, 0.8, 0.2, 0},
+		{-0.5, 1, 0, 0},
+		{-0.4, 0.8, 0.2, 0},
+		{0.8, 0.1, 0.3, 0.5},
+		{-0.6, 0.1, 0.3, 0.6},
+	}
+	m, err := FromReader(bytes.NewReader(testModelData(t, words, vecs)), opts...)
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	return m
+}
+
+func TestBiasSubspace(t *testing.T) {
+	m := testBiasModel(t)
+	pairs := [][2]string{{"he", "she"}, {"man", "woman"}}
+	b, err := NewBiasSubspace(m, pairs, 1)
+	if err != nil {
+		t.Fatalf("unexpected error from NewBiasSubspace: %v", err)
+	}
+	if b.Dim() != 1 || !approxEqual(float32(b.Variance), 1) {
+		t.Errorf("subspace dim %d, variance %v, expected dim 1, variance 1", b.Dim(), b.Variance)
+	}
+	tests := []struct {
+		word string
+		bias float32
+	}{
+		{"he", float32(1 / math.Sqrt2)},
+		{"nurse", float32(0.5 / math.Sqrt(1.25))},
+		{"engineer", float32(0.5 / math.Sqrt(1.25))},
+	}
+	for _, tt := range tests {
+		if bias := b.Bias(m.Map([]string{tt.word})[tt.word]); !approxEqual(bias, tt.bias) {
+			t.Errorf("Bias(%q) = %v, expected %v", tt.word, bias, tt.bias)
+		}
+	}
+
+	for _, k := range []int{0, 5} {
+		if _, err := NewBiasSubspace(m, pairs, k); err == nil {
+			t.Errorf("NewBiasSubspace() with k = %d returned nil error", k)
+		}
+	}
+	if _, err := NewBiasSubspace(m, [][2]string{{"he", "her"}}, 1); err == nil {
+		t.Errorf("NewBiasSubspace() with unknown word returned nil error")
+	}
+	if _, err := NewBiasSubspace(m, nil, 1); err == nil {
+		t.Errorf("NewBiasSubspace() with no pairs returned nil error")
+	}
+	if _, err := NewBiasSubspace(m, [][2]string{{"he", "he"}}, 1); err == nil {
+		t.Errorf("NewBiasSubspace() with identical pair returned nil error")
+	}
+	if _, err := NewBiasSubspace(m, pairs, 2); err == nil {
+		t.Errorf("NewBiasSubspace() with k greater than the rank of the differences returned nil error")
+	}
+}
+
+func TestDebias(t *testing.T) {
+	pairs := [][2]string{{"he", "she"}, {"man", "woman"}}
+	config := DebiasConfig{
+		Exclude:  []string{"he", "she", "man", "woman", "king"},
+		Equalize: [][2]string{{"grandfather", "grandmother"}, {"king", "queen"}},
+	}
+
+	for _, raw := range []bool{false, true} {
+		var opts []Option
+		if raw {
+			opts = append(opts, RawVectors())
+		}
+		m := testBiasModel(t, opts...)
+		b, err := NewBiasSubspace(m, pairs, 1)
+		if err != nil {
+			t.Fatalf("[raw %v] unexpected error from NewBiasSubspace: %v", raw, err)
+		}
+		d, err := b.Debias(m, config)
+		if err != nil {
+			t.Fatalf("[raw %v] unexpected error from Debias: %v", raw, err)
+		}
+		vecs := d.Map([]string{"he", "nurse", "engineer", "grandfather", "grandmother"})
+
+		// Neutralised words have no bias, and excluded words are unchanged.
+		for _, w := range []string{"nurse", "engineer"} {
+			if bias := b.Bias(vecs[w]); !approxEqual(bias, 0) {
+				t.Errorf("[raw %v] Bias(%q) = %v after debiasing, expected 0", raw, w, bias)
+			}
+		}
+		if cos, _ := d.Cos(Expr{"nurse": 1}, Expr{"engineer": 1}); !approxEqual(cos, 1) {
+			t.Errorf("[raw %v] Cos(nurse, engineer) = %v after debiasing, expected 1", raw, cos)
+		}
+		if v := m.Map([]string{"he"})["he"]; !approxEqual(v.Dot(vecs["he"]), v.Dot(v)) {
+			t.Errorf("[raw %v] vector for \"he\" = %v after debiasing, expected %v", raw, vecs["he"], v)
+		}
+
+		// Equalised words are equally similar to neutralised words, and keep their norms.
+		for _, w := range []string{"nurse", "engineer"} {
+			u, _ := d.Cos(Expr{w: 1}, Expr{"grandfather": 1})
+			v, _ := d.Cos(Expr{w: 1}, Expr{"grandmother": 1})
+			if !approxEqual(u, v) {
+				t.Errorf("[raw %v] Cos(%q, grandfather) = %v, Cos(%q, grandmother) = %v, expected equal", raw, w, u, w, v)
+			}
+		}
+		for _, w := range []string{"grandfather", "grandmother"} {
+			expected := m.Map([]string{w})[w].Norm()
+			if n := vecs[w].Norm(); !approxEqual(n, expected) {
+				t.Errorf("[raw %v] norm of vector for %q = %v after equalising, expected %v", raw, w, n, expected)
+			}
+		}
+	}
+
+	// Partial debiasing only reduces the bias.
+	m := testBiasModel(t)
+	b, err := NewBiasSubspace(m, pairs, 1)
+	if err != nil {
+		t.Fatalf("unexpected error from NewBiasSubspace: %v", err)
+	}
+	d, err := b.Debias(m, DebiasConfig{Strength: 0.5})
+	if err != nil {
+		t.Fatalf("unexpected error from Debias: %v", err)
+	}
+	before := b.Bias(m.Map([]string{"nurse"})["nurse"])
+	after := b.Bias(d.Map([]string{"nurse"})["nurse"])
+	if after <= 0 || after >= before {
+		t.Errorf("Bias(nurse) = %v after partial debiasing, expected between 0 and %v", after, before)
+	}
+
+	if _, err := b.Debias(testLowRankModel(t, 10, 5, 2), DebiasConfig{}); err == nil {
+		t.Errorf("Debias() of model with different dimension returned nil error")
+	}
+}
+
+func TestWEAT(t *testing.T) {
+	m := testBiasModel(t)
+	x := []string{"engineer", "doctor"}
+	y := []string{"nurse", "teacher"}
+	a := []string{"he", "man"}
+	b := []string{"she", "woman"}
+
+	r, err := WEAT(m, x, y, a, b)
+	if err != nil {
+		t.Fatalf("unexpected error from WEAT: %v", err)
+	}
+	if r.Statistic <= 0 || r.EffectSize < 1.5 {
+		t.Errorf("WEAT() = %v, expected positive statistic and large effect size", r)
+	}
+	if r.PValue > 0.25 {
+		t.Errorf("WEAT() = %v, expected p-value at most 0.25", r)
+	}
+
+	// Reversing the attribute sets reverses the association.
+	rev, err := WEAT(m, x, y, b, a)
+	if err != nil {
+		t.Fatalf("unexpected error from WEAT: %v", err)
+	}
+	if !approxEqual(float32(rev.EffectSize), float32(-r.EffectSize)) {
+		t.Errorf("WEAT() effect size = %v with reversed attributes, expected %v", rev.EffectSize, -r.EffectSize)
+	}
+
+	bs, err := NewBiasSubspace(m, [][2]string{{"he", "she"}, {"man", "woman"}}, 1)
+	if err != nil {
+		t.Fatalf("unexpected error from NewBiasSubspace: %v", err)
+	}
+	d, err := bs.Debias(m, DebiasConfig{Exclude: append(a, b...)})
+	if err != nil {
+		t.Fatalf("unexpected error from Debias: %v", err)
+	}
+	r, err = WEAT(d, x, y, a, b)
+	if err != nil {
+		t.Fatalf("unexpected error from WEAT: %v", err)
+	}
+	if math.Abs(r.Statistic) > 1e-5 || math.Abs(r.EffectSize) > 1e-3 {
+		t.Errorf("WEAT() = %v after debiasing, expected no association", r)
+	}
+
+	if _, err := WEAT(m, x, nil, a, b); err == nil {
+		t.Errorf("WEAT() with empty target set returned nil error")
+	}
+	if _, err := WEAT(m, x, []string{"nobody"}, a, b); err == nil {
+		t.Errorf("WEAT() with unknown word returned nil error")
+	}
+}
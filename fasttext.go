@@ -0,0 +1,378 @@
+package word2vec
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	fastTextMagic   = 793712314
+	fastTextVersion = 12
+)
+
+// isFastText reports whether b (the beginning of model data) is a fastText binary model.
+func isFastText(b []byte) bool {
+	return len(b) >= 4 && binary.LittleEndian.Uint32(b) == fastTextMagic
+}
+
+// Subwords is an Option which keeps the character n-gram vectors of fastText models (see
+// FromFastText), so that vectors are synthesised from the n-grams of words which are not
+// in the vocabulary.  Such words can then be used in Eval, Map and CosN rather than
+// causing a NotFoundError (if Fold is also given, such words are folded before their
+// n-grams are taken).  The n-gram vectors can be large, and are not kept by default.
+// They are not written out by WriteBinary, WriteText or WriteMmap.  Loading returns an
+// error if AllButTheTop is also given, as the n-gram vectors are not post-processed.
+func Subwords() Option {
+	return func(o *options) {
+		o.subwords = true
+	}
+}
+
+// fastTextArgs are the training arguments stored in fastText models.
+type fastTextArgs struct {
+	Dim, WS, Epoch, MinCount, Neg, WordNgrams, Loss, Model int32
+	Bucket, Minn, Maxn, LRUpdateRate                       int32
+	T                                                      float64
+}
+
+// FromFastText creates a Model using the fastText binary model data (.bin) provided by
+// the io.Reader.  The vectors of the words in the model are computed from their character
+// n-grams as fastText does, so they match those in the corresponding .vec file.  Labels
+// of supervised models are not included, and quantised models (.ftz) are not supported.
+// Errors in the model data are reported using ParseError.
+func FromFastText(r io.Reader, opts ...Option) (*Model, error) {
+	return fromFastText(context.Background(), r, newOptions(opts))
+}
+
+func fromFastText(ctx context.Context, r io.Reader, o *options) (*Model, error) {
+	if o.subwords && o.postProcess {
+		return nil, errors.New("cannot use Subwords with AllButTheTop: subword vectors are not post-processed")
+	}
+	p := newParser(r, o)
+
+	var header struct {
+		Magic, Version int32
+		Args           fastTextArgs
+	}
+	if err := p.read(&header); err != nil {
+		return nil, err
+	}
+	if header.Magic != fastTextMagic {
+		return nil, p.errorf("invalid fastText magic number")
+	}
+	if header.Version != fastTextVersion {
+		return nil, p.errorf("unsupported fastText version %d", header.Version)
+	}
+
+	args := header.Args
+	dim := int(args.Dim)
+	if args.Bucket < 0 || int(args.Bucket) > p.o.limits.Size {
+		return nil, p.errorf("invalid bucket count %d (limit %d)", args.Bucket, p.o.limits.Size)
+	}
+
+	var dict struct {
+		Size, Words, Labels int32
+		Tokens, PruneSize   int64
+	}
+	if err := p.read(&dict); err != nil {
+		return nil, err
+	}
+	if err := p.checkHeader(int(dict.Size), dim); err != nil {
+		return nil, err
+	}
+	if dict.Words < 0 || dict.Words > dict.Size || dict.PruneSize > int64(args.Bucket) {
+		return nil, p.errorf("invalid fastText dictionary")
+	}
+
+	words := make([]string, 0, p.prealloc(int(dict.Words), 1, 10))
+	for i := 0; i < int(dict.Size); i++ {
+		p.next()
+		w, err := p.fastTextWord()
+		if err != nil {
+			return nil, err
+		}
+		var entry struct {
+			Count int64
+			Type  int8
+		}
+		if err := p.read(&entry); err != nil {
+			return nil, err
+		}
+		if i < int(dict.Words) {
+			if entry.Type != 0 {
+				return nil, p.errorf("expected word entry, got type %d", entry.Type)
+			}
+			words = append(words, w)
+		}
+	}
+
+	p.next()
+	sub := &subwords{
+		minn:   int(args.Minn),
+		maxn:   int(args.Maxn),
+		bucket: int(args.Bucket),
+	}
+	rows := int(args.Bucket)
+	if dict.PruneSize >= 0 {
+		rows = int(dict.PruneSize)
+		sub.prune = make(map[int]int, rows)
+		for i := 0; i < rows; i++ {
+			var pair [2]int32
+			if err := p.read(&pair); err != nil {
+				return nil, err
+			}
+			if pair[0] < 0 || int(pair[0]) >= sub.bucket || pair[1] < 0 || int(pair[1]) >= rows {
+				return nil, p.errorf("invalid pruned n-gram index")
+			}
+			sub.prune[int(pair[0])] = int(pair[1])
+		}
+	}
+
+	var matrix struct {
+		Quantised bool
+		M, N      int64
+	}
+	if err := p.read(&matrix); err != nil {
+		return nil, err
+	}
+	if matrix.Quantised {
+		return nil, p.errorf("quantised fastText models are not supported")
+	}
+	if matrix.N != int64(dim) || matrix.M != int64(len(words)+rows) {
+		return nil, p.errorf("input matrix is %dx%d, expected %dx%d", matrix.M, matrix.N, len(words)+rows, dim)
+	}
+
+	vecs, ngrams, err := p.fastTextMatrix(ctx, int(matrix.M), len(words), dim)
+	if err != nil {
+		return nil, err
+	}
+	sub.vecs = ngrams
+
+	// Compute the word vectors in place: the rows of the n-gram vectors are not modified.
+	parallel(len(words), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			v := Vector(vecs[i*dim : (i+1)*dim])
+			k := 1
+			sub.ngrams(words[i], func(j int) {
+				v.Add(1, sub.row(j, dim))
+				k++
+			})
+			for j := range v {
+				v[j] /= float32(k)
+			}
+		}
+	})
+
+	b := newBuilder(p.o, len(words), dim)
+	for i, w := range words {
+		if err := b.add(w, Vector(vecs[i*dim:(i+1)*dim])); err != nil {
+			return nil, p.errorf("%w: %q", err, w)
+		}
+	}
+	m, err := b.model()
+	if err != nil {
+		return nil, err
+	}
+	if p.o.subwords {
+		sub.fold = p.o.fold
+		m.sub = sub
+	}
+	return m, nil
+}
+
+// read reads little-endian binary data into v (see binary.Read).
+func (p *parser) read(v interface{}) error {
+	if err := binary.Read(p.br, binary.LittleEndian, v); err != nil {
+		return p.error(err)
+	}
+	p.off += int64(binary.Size(v))
+	return nil
+}
+
+// fastTextWord reads a null-terminated word from fastText model data.
+func (p *parser) fastTextWord() (string, error) {
+	buf := p.buf[:0]
+	for {
+		c, err := p.br.ReadByte()
+		if err != nil {
+			return "", p.error(err)
+		}
+		p.off++
+		if c == 0 {
+			break
+		}
+		if len(buf) == p.o.limits.WordLength {
+			return "", p.errorf("word too long (limit %d bytes)", p.o.limits.WordLength)
+		}
+		buf = append(buf, c)
+	}
+	p.buf = buf
+	if len(buf) == 0 {
+		return "", p.error(errEmptyWord)
+	}
+	return string(buf), nil
+}
+
+// fastTextMatrix reads the rows x dim matrix of vectors from fastText model data, and
+// returns its first split rows (the word vectors) and the remaining rows (the n-gram
+// vectors) separately, so that either can be kept without the other.  The matrix is read
+// in parts, so that ctx and progress are checked periodically, and the memory allocated
+// is bounded by the amount of data read.
+func (p *parser) fastTextMatrix(ctx context.Context, rows, split, dim int) (words, ngrams []float32, err error) {
+	words = make([]float32, 0, p.prealloc(split, dim, 4*dim))
+	ngrams = make([]float32, 0, p.prealloc(rows-split, dim, 4*dim))
+	buf := make([]byte, 4*dim*batchSize)
+	for i := 0; i < rows; i += batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		k := rows - i
+		if k > batchSize {
+			k = batchSize
+		}
+		b := buf[:4*k*dim]
+		n, err := io.ReadFull(p.br, b)
+		p.off += int64(n)
+		if err != nil {
+			return nil, nil, p.error(err)
+		}
+
+		w := split - i
+		if w < 0 {
+			w = 0
+		} else if w > k {
+			w = k
+		}
+		words = append(words, make([]float32, w*dim)...)
+		ngrams = append(ngrams, make([]float32, (k-w)*dim)...)
+		for j := 0; j < k; j++ {
+			var v Vector
+			if r := i + j; r < split {
+				v = Vector(words[r*dim : (r+1)*dim])
+			} else {
+				v = Vector(ngrams[(r-split)*dim : (r-split+1)*dim])
+			}
+			if err := decodeVector(b[4*j*dim:], v); err != nil {
+				return nil, nil, p.errorf("row %d: %v", i+j, err)
+			}
+		}
+
+		if p.o.progress != nil {
+			p.o.progress(Progress{
+				Entries:    i + k,
+				Size:       rows,
+				Bytes:      p.off,
+				TotalBytes: p.o.dataSize,
+			})
+		}
+	}
+	return words, ngrams, nil
+}
+
+// subwords are the character n-gram vectors of a fastText model, used to compute
+// vectors for words which are not in the vocabulary.
+type subwords struct {
+	minn, maxn int
+	bucket     int
+	prune      map[int]int         // n-gram bucket -> row, nil if the model is not pruned
+	vecs       []float32           // n-gram vectors, as in the model data
+	fold       func(string) string // applied to words before their n-grams are taken, nil if none (see Fold)
+}
+
+func (s *subwords) row(i, dim int) Vector {
+	return Vector(s.vecs[i*dim : (i+1)*dim])
+}
+
+// ngrams calls f with the row of each character n-gram of w, as computed by fastText.
+// N-grams are taken from w surrounded by "<" and ">", and are made up of whole UTF-8
+// characters.
+func (s *subwords) ngrams(w string, f func(row int)) {
+	if s.bucket == 0 || s.maxn <= 0 {
+		return
+	}
+	w = "<" + w + ">"
+	for i := 0; i < len(w); i++ {
+		if w[i]&0xC0 == 0x80 {
+			continue
+		}
+		j := i
+		for n := 1; j < len(w) && n <= s.maxn; n++ {
+			j++
+			for j < len(w) && w[j]&0xC0 == 0x80 {
+				j++
+			}
+			if n < s.minn || (n == 1 && (i == 0 || j == len(w))) {
+				continue
+			}
+			h := int(fastTextHash(w[i:j]) % uint32(s.bucket))
+			if s.prune != nil {
+				var ok bool
+				if h, ok = s.prune[h]; !ok {
+					continue
+				}
+			}
+			f(h)
+		}
+	}
+}
+
+// vector returns the vector for w (folded, if the model was loaded with Fold) computed as
+// the average of its n-gram vectors, or false if w has no n-grams.
+func (s *subwords) vector(w string, dim int) (Vector, bool) {
+	if s.fold != nil {
+		w = s.fold(w)
+	}
+	v := make(Vector, dim)
+	k := 0
+	s.ngrams(w, func(j int) {
+		v.Add(1, s.row(j, dim))
+		k++
+	})
+	if k == 0 {
+		return nil, false
+	}
+	for j := range v {
+		v[j] /= float32(k)
+	}
+	return v, true
+}
+
+// fastTextHash is the FNV-1a hash used by fastText, which sign-extends bytes.
+func fastTextHash(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(int8(s[i]))
+		h *= 16777619
+	}
+	return h
+}
+
+// vector returns the vector for w (normalised unless the model has raw vectors) and its
+// norm in the model data.  Words which are not in the vocabulary are synthesised from
+// their n-grams if the model has subword information (see Subwords).
+func (m *Model) vector(w string) (Vector, float32, bool) {
+	if i, ok := m.vocab.id(w); ok {
+		return m.row(i), m.norm(i), true
+	}
+	if m.sub == nil {
+		return nil, 0, false
+	}
+	v, ok := m.sub.vector(w, m.dim)
+	if !ok {
+		return nil, 0, false
+	}
+	n := v.Norm()
+	if !m.raw && n != 0 {
+		v.Normalise()
+	}
+	return v, n, true
+}
+
+// HasSubwords reports whether the model synthesises vectors for words which are not in
+// its vocabulary (see Subwords).
+func (m *Model) HasSubwords() bool {
+	return m.sub != nil
+}
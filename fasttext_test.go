@@ -0,0 +1,141 @@
+package word2vec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// testFastTextData returns fastText model data with the given words, and n-gram vectors
+// in bucket rows.
+func testFastTextData(t testing.TB, words []string, vecs []Vector, minn, maxn int, buckets []Vector) []byte {
+	dim := len(vecs[0])
+	buf := &bytes.Buffer{}
+	write := func(v interface{}) {
+		if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
+			t.Fatalf("unexpected error writing fastText data: %v", err)
+		}
+	}
+
+	write([]int32{fastTextMagic, fastTextVersion})
+	write(fastTextArgs{Dim: int32(dim), Bucket: int32(len(buckets)), Minn: int32(minn), Maxn: int32(maxn)})
+	write([]int32{int32(len(words)), int32(len(words)), 0})
+	write([]int64{int64(len(words)), -1})
+	for _, w := range words {
+		buf.WriteString(w)
+		buf.WriteByte(0)
+		write(int64(1))
+		write(int8(0))
+	}
+	write(false)
+	write([]int64{int64(len(words) + len(buckets)), int64(dim)})
+	for _, v := range append(vecs, buckets...) {
+		write(v)
+	}
+	return buf.Bytes()
+}
+
+func TestFastTextHash(t *testing.T) {
+	tests := []struct {
+		s    string
+		hash uint32
+	}{
+		{"", 2166136261},
+		{"a", 0xe40c292c},
+		{"\xe9", 0xebf38b44}, // bytes are sign-extended (0x6c0b6c44 otherwise)
+	}
+
+	for _, tt := range tests {
+		if h := fastTextHash(tt.s); h != tt.hash {
+			t.Errorf("fastTextHash(%q) = %#x, expected %#x", tt.s, h, tt.hash)
+		}
+	}
+}
+
+func TestSubwordNgrams(t *testing.T) {
+	s := &subwords{minn: 2, maxn: 3, bucket: 1 << 20}
+	var rows []int
+	s.ngrams("aé", func(row int) { rows = append(rows, row) })
+
+	var expected []int
+	for _, g := range []string{"<a", "<aé", "aé", "aé>", "é>"} {
+		expected = append(expected, int(fastTextHash(g)%(1<<20)))
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("ngrams() = %v, expected %v", rows, expected)
+	}
+	for i := range rows {
+		if rows[i] != expected[i] {
+			t.Errorf("ngrams()[%d] = %d, expected %d", i, rows[i], expected[i])
+		}
+	}
+}
+
+func TestFromFastText(t *testing.T) {
+	buckets := []Vector{{1, 0}, {0, 1}, {1, 1}, {0, 0}, {2, 0}}
+	s := &subwords{minn: 3, maxn: 3, bucket: len(buckets)}
+	words := []string{"hello", "world"}
+	data := testFastTextData(t, words, []Vector{{1, 0}, {0, 1}}, s.minn, s.maxn, buckets)
+
+	// Expected vector for w (before normalisation): the average of v and the n-grams.
+	average := func(w string, v Vector) Vector {
+		u := append(Vector(nil), v...)
+		k := 0
+		if v != nil {
+			k = 1
+		} else {
+			u = make(Vector, 2)
+		}
+		s.ngrams(w, func(j int) {
+			u.Add(1, buckets[j])
+			k++
+		})
+		for j := range u {
+			u[j] /= float32(k)
+		}
+		return u
+	}
+
+	m, err := Load(bytes.NewReader(data), RawVectors())
+	if err != nil {
+		t.Fatalf("unexpected error from Load: %v", err)
+	}
+	if m.HasSubwords() {
+		t.Errorf("m.HasSubwords() = true, expected false")
+	}
+	v := m.Map(words)["hello"]
+	if expected := average("hello", Vector{1, 0}); !approxEqual(v[0], expected[0]) || !approxEqual(v[1], expected[1]) {
+		t.Errorf("vector for hello = %v, expected %v", v, expected)
+	}
+	if _, err := m.Eval(Expr{"helo": 1}); !errors.As(err, new(*NotFoundError)) {
+		t.Errorf("m.Eval() error = %v, expected NotFoundError", err)
+	}
+
+	m, err = FromFastText(bytes.NewReader(data), Subwords(), RawVectors())
+	if err != nil {
+		t.Fatalf("unexpected error from FromFastText: %v", err)
+	}
+	if !m.HasSubwords() {
+		t.Errorf("m.HasSubwords() = false, expected true")
+	}
+	v = m.Map([]string{"helo"})["helo"]
+	if expected := average("helo", nil); v == nil || !approxEqual(v[0], expected[0]) || !approxEqual(v[1], expected[1]) {
+		t.Errorf("vector for helo = %v, expected %v", v, expected)
+	}
+	if _, err := m.CosN(Expr{"helo": 1}, 1); err != nil {
+		t.Errorf("unexpected error from CosN: %v", err)
+	}
+	if m.Size() != 2 {
+		t.Errorf("m.Size() = %d, expected 2", m.Size())
+	}
+
+	m, err = FromFastText(bytes.NewReader(data), Subwords(), RawVectors(), FoldCase())
+	if err != nil {
+		t.Fatalf("unexpected error from FromFastText: %v", err)
+	}
+	v = m.Map([]string{"HeLo"})["HeLo"]
+	if expected := average("helo", nil); v == nil || !approxEqual(v[0], expected[0]) || !approxEqual(v[1], expected[1]) {
+		t.Errorf("vector for HeLo with FoldCase = %v, expected %v", v, expected)
+	}
+}
@@ -0,0 +1,583 @@
+package word2vec
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// HNSWConfig is a type which configures an HNSW index (see NewHNSW).  Zero fields take
+// their default values.
+type HNSWConfig struct {
+	// M is the number of neighbours linked to each word on each layer of the graph (twice
+	// as many on the bottom layer).  Larger values give better recall, at the cost of
+	// memory and build time.  Defaults to 16; values are limited to between 2 and 65536.
+	M int
+
+	// EfConstruction is the number of candidate neighbours considered when a word is
+	// added to the graph.  Larger values give a better graph, at the cost of build time.
+	// Defaults to 200.
+	EfConstruction int
+
+	// EfSearch is the number of candidate neighbours considered by each query (at least
+	// the number of matches requested).  Larger values give better recall, at the cost
+	// of query time.  Defaults to 64.
+	EfSearch int
+
+	// Workers is the number of goroutines which add words to the graph.  Defaults to
+	// GOMAXPROCS.
+	Workers int
+
+	// Seed seeds the random layers of the words.
+	Seed int64
+}
+
+// HNSW is a Coser which finds the nearest neighbours of expressions approximately, by
+// searching a hierarchical navigable small world graph of the words of a model (Malkov
+// and Yashunin, 2016), rather than comparing every word in the model.  Cos and Coses are
+// computed by the model.  An HNSW can be used concurrently.
+type HNSW struct {
+	m        *Model
+	c        HNSWConfig
+	vecs     *float32Storage // normalised vectors of the model
+	links    [][][]int32     // neighbours of each word on each of its layers
+	locks    []sync.Mutex    // guard links while the graph is built
+	entry    int32
+	maxLevel int
+	mu       sync.RWMutex // guards entry and maxLevel while the graph is built
+	visited  sync.Pool
+}
+
+// NewHNSW builds an HNSW index of the words of m.  The graph is built by c.Workers
+// goroutines, which each add words in turn, in order of their ID.  The index uses the
+// vectors of m, so m must not be closed while it is used.  Unless the vectors of m are
+// normalised and stored as float32, the index keeps a normalised float32 copy of them.
+func NewHNSW(m *Model, c HNSWConfig) *HNSW {
+	if c.M <= 0 {
+		c.M = 16
+	} else if c.M < 2 {
+		c.M = 2
+	} else if c.M > maxHNSWM {
+		c.M = maxHNSWM
+	}
+	if c.EfConstruction <= 0 {
+		c.EfConstruction = 200
+	}
+	if c.EfSearch <= 0 {
+		c.EfSearch = 64
+	}
+	if c.Workers <= 0 {
+		c.Workers = runtime.GOMAXPROCS(0)
+	}
+	h := newHNSW(m, c)
+
+	size := m.vocab.size()
+	if size == 0 {
+		return h
+	}
+	r := rand.New(rand.NewSource(c.Seed))
+	ml := 1 / math.Log(float64(c.M))
+	for i := range h.links {
+		level := int(-math.Log(1-r.Float64()) * ml)
+		// The number of layers of each word is written as a byte by Write.
+		if level > math.MaxUint8-1 {
+			level = math.MaxUint8 - 1
+		}
+		h.links[i] = make([][]int32, level+1)
+	}
+	h.entry, h.maxLevel = 0, len(h.links[0])-1
+
+	next := int64(0)
+	var wg sync.WaitGroup
+	wg.Add(c.Workers)
+	for w := 0; w < c.Workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := atomic.AddInt64(&next, 1)
+				if i >= int64(size) {
+					return
+				}
+				h.insert(int32(i))
+			}
+		}()
+	}
+	wg.Wait()
+	return h
+}
+
+// newHNSW returns an HNSW index of m with no links.
+func newHNSW(m *Model, c HNSWConfig) *HNSW {
+	size := m.vocab.size()
+	h := &HNSW{
+		m:     m,
+		c:     c,
+		links: make([][][]int32, size),
+		locks: make([]sync.Mutex, size),
+	}
+	if st, ok := m.store.(*float32Storage); ok && !m.raw {
+		h.vecs = st
+	} else {
+		h.vecs = &float32Storage{dim: m.dim, vecs: make([]float32, size*m.dim)}
+		parallel(size, func(lo, hi int) {
+			for i := lo; i < hi; i++ {
+				v := h.vecs.row(i)
+				copy(v, m.row(i))
+				if v.Norm() != 0 {
+					v.Normalise()
+				}
+			}
+		})
+	}
+	h.visited.New = func() interface{} { return &visitedSet{marks: make([]uint32, size)} }
+	return h
+}
+
+// SetEfSearch sets the number of candidate neighbours considered by each query (see
+// HNSWConfig).  It must not be called concurrently with CosN.
+func (h *HNSW) SetEfSearch(ef int) {
+	if ef <= 0 {
+		ef = 64
+	}
+	h.c.EfSearch = ef
+}
+
+// Cos implements Coser.
+func (h *HNSW) Cos(e, f Expr) (float32, error) {
+	return h.m.Cos(e, f)
+}
+
+// Coses implements Coser.
+func (h *HNSW) Coses(pairs [][2]Expr) ([]float32, error) {
+	return h.m.Coses(pairs)
+}
+
+// CosN implements Coser.  The matches are scored as by Model.CosN, but are found by
+// searching the graph, so they may not be the n most similar words.  Scores using
+// DotProduct similarity are approximated less well, as the graph links words with
+// similar directions.
+func (h *HNSW) CosN(e Expr, n int) ([]Match, error) {
+	if n == 0 {
+		return nil, nil
+	}
+
+	v, err := e.Eval(h.m)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.m.sim == Cosine {
+		v.Normalise()
+	}
+	return h.search(v, n), nil
+}
+
+// Info returns the description of the model (see Model.Info).
+func (h *HNSW) Info() (Info, error) {
+	return h.m.Info()
+}
+
+// hnswCandidate is a word considered by a search of the graph, and its similarity to
+// the query.
+type hnswCandidate struct {
+	id  int32
+	sim float32
+}
+
+// candidateHeap is a heap of candidates with the least similar first, or the most
+// similar first if max is set.
+type candidateHeap struct {
+	c   []hnswCandidate
+	max bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.c) }
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.max {
+		return h.c[i].sim > h.c[j].sim
+	}
+	return h.c[i].sim < h.c[j].sim
+}
+func (h *candidateHeap) Swap(i, j int)      { h.c[i], h.c[j] = h.c[j], h.c[i] }
+func (h *candidateHeap) Push(x interface{}) { h.c = append(h.c, x.(hnswCandidate)) }
+
+func (h *candidateHeap) Pop() interface{} {
+	x := h.c[len(h.c)-1]
+	h.c = h.c[:len(h.c)-1]
+	return x
+}
+
+// visitedSet records the words visited by a search.  Words are visited if their mark is
+// the current generation, so the set is cleared by incrementing it.
+type visitedSet struct {
+	marks []uint32
+	gen   uint32
+}
+
+func (s *visitedSet) clear() {
+	s.gen++
+	if s.gen == 0 {
+		for i := range s.marks {
+			s.marks[i] = 0
+		}
+		s.gen = 1
+	}
+}
+
+// visit marks i as visited, and returns whether it was already.
+func (s *visitedSet) visit(i int32) bool {
+	if s.marks[i] == s.gen {
+		return true
+	}
+	s.marks[i] = s.gen
+	return false
+}
+
+// neighbors returns the neighbours of i on the given layer.
+func (h *HNSW) neighbors(i int32, layer int) []int32 {
+	h.locks[i].Lock()
+	n := h.links[i][layer]
+	h.locks[i].Unlock()
+	return n
+}
+
+// maxLinks returns the maximum number of neighbours of a word on the given layer.
+func (h *HNSW) maxLinks(layer int) int {
+	if layer == 0 {
+		return 2 * h.c.M
+	}
+	return h.c.M
+}
+
+// greedy returns the word most similar to q found by moving from cur to more similar
+// neighbours on the given layer.
+func (h *HNSW) greedy(q Vector, cur hnswCandidate, layer int) hnswCandidate {
+	for changed := true; changed; {
+		changed = false
+		for _, j := range h.neighbors(cur.id, layer) {
+			if s := q.Dot(h.vecs.row(int(j))); s > cur.sim {
+				cur, changed = hnswCandidate{j, s}, true
+			}
+		}
+	}
+	return cur
+}
+
+// searchLayer returns (in no particular order) the ef words most similar to q found on
+// the given layer, starting from the words in entry.
+func (h *HNSW) searchLayer(q Vector, entry []hnswCandidate, ef, layer int, visited *visitedSet) []hnswCandidate {
+	visited.clear()
+	candidates := &candidateHeap{max: true}
+	results := &candidateHeap{}
+	for _, e := range entry {
+		visited.visit(e.id)
+		heap.Push(candidates, e)
+		heap.Push(results, e)
+		if results.Len() > ef {
+			heap.Pop(results)
+		}
+	}
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && c.sim < results.c[0].sim {
+			break
+		}
+		for _, j := range h.neighbors(c.id, layer) {
+			if visited.visit(j) {
+				continue
+			}
+			s := q.Dot(h.vecs.row(int(j)))
+			if results.Len() < ef || s > results.c[0].sim {
+				heap.Push(candidates, hnswCandidate{j, s})
+				heap.Push(results, hnswCandidate{j, s})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+	return results.c
+}
+
+// selectNeighbors returns up to n of the candidates (sorted by descending similarity),
+// preferring candidates which are more similar to the word than to those already
+// selected, so that the neighbours of the word are spread in different directions.
+func (h *HNSW) selectNeighbors(candidates []hnswCandidate, n int) []int32 {
+	selected := make([]int32, 0, n)
+	for _, c := range candidates {
+		if len(selected) >= n {
+			break
+		}
+		v := h.vecs.row(int(c.id))
+		keep := true
+		for _, s := range selected {
+			if v.Dot(h.vecs.row(int(s))) > c.sim {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, c.id)
+		}
+	}
+	return selected
+}
+
+// insert adds the word i to the graph.
+func (h *HNSW) insert(i int32) {
+	q := h.vecs.row(int(i))
+	level := len(h.links[i]) - 1
+	h.mu.RLock()
+	entry, top := h.entry, h.maxLevel
+	h.mu.RUnlock()
+
+	visited := h.visited.Get().(*visitedSet)
+	defer h.visited.Put(visited)
+
+	cur := hnswCandidate{entry, q.Dot(h.vecs.row(int(entry)))}
+	for layer := top; layer > level; layer-- {
+		cur = h.greedy(q, cur, layer)
+	}
+	candidates := []hnswCandidate{cur}
+	if level < top {
+		top = level
+	}
+	for layer := top; layer >= 0; layer-- {
+		candidates = h.searchLayer(q, candidates, h.c.EfConstruction, layer, visited)
+		sorted := append([]hnswCandidate(nil), candidates...)
+		sort.Slice(sorted, func(a, b int) bool { return sorted[a].sim > sorted[b].sim })
+		neighbors := h.selectNeighbors(sorted, h.c.M)
+
+		h.locks[i].Lock()
+		h.links[i][layer] = neighbors
+		h.locks[i].Unlock()
+		for _, j := range neighbors {
+			h.link(j, i, layer)
+		}
+	}
+
+	if level > top {
+		h.mu.Lock()
+		if level > h.maxLevel {
+			h.entry, h.maxLevel = i, level
+		}
+		h.mu.Unlock()
+	}
+}
+
+// link adds i to the neighbours of j on the given layer, re-selecting the neighbours of
+// j if it has too many.  The neighbours are replaced rather than modified, so slices
+// returned by neighbors are not changed.
+func (h *HNSW) link(j, i int32, layer int) {
+	h.locks[j].Lock()
+	defer h.locks[j].Unlock()
+
+	links := h.links[j][layer]
+	if len(links) < h.maxLinks(layer) {
+		h.links[j][layer] = append(links[:len(links):len(links)], i)
+		return
+	}
+	v := h.vecs.row(int(j))
+	candidates := make([]hnswCandidate, 0, len(links)+1)
+	for _, k := range append(links[:len(links):len(links)], i) {
+		candidates = append(candidates, hnswCandidate{k, v.Dot(h.vecs.row(int(k)))})
+	}
+	sort.Slice(candidates, func(a, b int) bool { return candidates[a].sim > candidates[b].sim })
+	h.links[j][layer] = h.selectNeighbors(candidates, h.maxLinks(layer))
+}
+
+// search returns the n best matches for v found by searching the graph, scored as by
+// Model.cosineN.
+func (h *HNSW) search(v Vector, n int) []Match {
+	if len(h.links) == 0 {
+		return nil
+	}
+	q := append(Vector(nil), v...)
+	if q.Norm() != 0 {
+		q.Normalise()
+	}
+
+	visited := h.visited.Get().(*visitedSet)
+	defer h.visited.Put(visited)
+
+	cur := hnswCandidate{h.entry, q.Dot(h.vecs.row(int(h.entry)))}
+	for layer := h.maxLevel; layer > 0; layer-- {
+		cur = h.greedy(q, cur, layer)
+	}
+	ef := h.c.EfSearch
+	if ef < n {
+		ef = n
+	}
+	candidates := h.searchLayer(q, []hnswCandidate{cur}, ef, 0, visited)
+
+	r := make([]Match, len(candidates))
+	for k, c := range candidates {
+		i := int(c.id)
+		r[k] = Match{h.m.vocab.word(i), h.m.store.dot(v, i) * h.m.scale(i)}
+	}
+	sort.Slice(r, func(a, b int) bool { return r[a].Score > r[b].Score })
+	if len(r) > n {
+		r = r[:n]
+	}
+	return r
+}
+
+// Layout of HNSW index data written by HNSW.Write: a header (see hnswHeader), then for
+// each word in ID order its number of layers (uint8), and for each layer the number of
+// neighbours (uint32) followed by their IDs (uint32).  All values are little-endian.
+const (
+	hnswMagic   = "W2VH"
+	hnswVersion = 1
+
+	// maxHNSWM bounds the M of an index (see HNSWConfig).
+	maxHNSWM = 1 << 16
+
+	// hnswChunk is the number of neighbour IDs read at a time by ReadHNSW, so that a
+	// corrupt count is detected at the end of the data rather than by allocating it.
+	hnswChunk = 1024
+)
+
+// hnswHeader is the header of HNSW index data.
+type hnswHeader struct {
+	Magic          [4]byte
+	Version        uint32
+	Size           uint64
+	M              uint32
+	EfConstruction uint32
+	EfSearch       uint32
+	Entry          uint32
+	MaxLevel       uint32
+	Fingerprint    [16]byte // of the model the index was built from, see Model.Fingerprint
+}
+
+// Write writes the index to w, in a format which can be read by ReadHNSW.  The vectors
+// of the model are not written.
+func (h *HNSW) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	hdr := hnswHeader{
+		Version:        hnswVersion,
+		Size:           uint64(len(h.links)),
+		M:              uint32(h.c.M),
+		EfConstruction: uint32(h.c.EfConstruction),
+		EfSearch:       uint32(h.c.EfSearch),
+		Entry:          uint32(h.entry),
+		MaxLevel:       uint32(h.maxLevel),
+		Fingerprint:    h.m.fp,
+	}
+	copy(hdr.Magic[:], hnswMagic)
+	if err := binary.Write(bw, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	for _, layers := range h.links {
+		if err := bw.WriteByte(uint8(len(layers))); err != nil {
+			return err
+		}
+		for _, links := range layers {
+			if err := binary.Write(bw, binary.LittleEndian, uint32(len(links))); err != nil {
+				return err
+			}
+			if err := binary.Write(bw, binary.LittleEndian, links); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadHNSW reads an HNSW index of m written by HNSW.Write.  Returns an error if the data
+// is invalid, or if the index was not built from m (or a model with the same
+// fingerprint, see Model.Fingerprint).
+func ReadHNSW(r io.Reader, m *Model) (*HNSW, error) {
+	br := bufio.NewReader(r)
+	var hdr hnswHeader
+	if err := binary.Read(br, binary.LittleEndian, &hdr); err != nil {
+		return nil, fmt.Errorf("error reading HNSW index header: %v", err)
+	}
+	if string(hdr.Magic[:]) != hnswMagic {
+		return nil, errors.New("invalid HNSW index data: bad magic")
+	}
+	if hdr.Version != hnswVersion {
+		return nil, fmt.Errorf("invalid HNSW index data: unsupported version %d", hdr.Version)
+	}
+	if hdr.Fingerprint != m.fp || hdr.Size != uint64(m.vocab.size()) {
+		return nil, fmt.Errorf("HNSW index was built from a different model (fingerprint %x, expected %v)", hdr.Fingerprint, m.Fingerprint())
+	}
+	if hdr.M == 0 || hdr.M > maxHNSWM || hdr.Size > 0 && (hdr.Entry >= uint32(hdr.Size) || hdr.MaxLevel > math.MaxUint8-1) {
+		return nil, errors.New("invalid HNSW index data: bad header")
+	}
+
+	h := newHNSW(m, HNSWConfig{
+		M:              int(hdr.M),
+		EfConstruction: int(hdr.EfConstruction),
+		EfSearch:       int(hdr.EfSearch),
+	})
+	h.entry, h.maxLevel = int32(hdr.Entry), int(hdr.MaxLevel)
+	for i := range h.links {
+		n, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("error reading HNSW index: %v", err)
+		}
+		if n == 0 || int(n)-1 > h.maxLevel {
+			return nil, fmt.Errorf("invalid HNSW index data: word %d has %d layers", i, n)
+		}
+		h.links[i] = make([][]int32, n)
+		for layer := range h.links[i] {
+			var count uint32
+			if err := binary.Read(br, binary.LittleEndian, &count); err != nil {
+				return nil, fmt.Errorf("error reading HNSW index: %v", err)
+			}
+			if int(count) > h.maxLinks(layer) {
+				return nil, fmt.Errorf("invalid HNSW index data: word %d has %d neighbours", i, count)
+			}
+			links, err := readHNSWLinks(br, int(count))
+			if err != nil {
+				return nil, fmt.Errorf("error reading HNSW index: %v", err)
+			}
+			for _, j := range links {
+				if j < 0 || int(j) >= len(h.links) {
+					return nil, fmt.Errorf("invalid HNSW index data: word %d has neighbour %d", i, j)
+				}
+			}
+			h.links[i][layer] = links
+		}
+	}
+	for i, layers := range h.links {
+		for layer, links := range layers {
+			for _, j := range links {
+				if len(h.links[j]) <= layer {
+					return nil, fmt.Errorf("invalid HNSW index data: word %d has neighbour %d on layer %d", i, j, layer)
+				}
+			}
+		}
+	}
+	if len(h.links) > 0 && len(h.links[h.entry])-1 != h.maxLevel {
+		return nil, errors.New("invalid HNSW index data: entry word is not on the top layer")
+	}
+	return h, nil
+}
+
+// readHNSWLinks reads count neighbour IDs from r, hnswChunk at a time.
+func readHNSWLinks(r io.Reader, count int) ([]int32, error) {
+	var links []int32
+	for len(links) < count {
+		k := count - len(links)
+		if k > hnswChunk {
+			k = hnswChunk
+		}
+		n := len(links)
+		links = append(links, make([]int32, k)...)
+		if err := binary.Read(r, binary.LittleEndian, links[n:]); err != nil {
+			return nil, err
+		}
+	}
+	return links, nil
+}
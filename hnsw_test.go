@@ -0,0 +1,118 @@
+package word2vec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestHNSW(t *testing.T) {
+	data := testRandomModelData(t, 2000, 16, 1)
+	tests := []struct {
+		name string
+		opts []Option
+		c    HNSWConfig
+	}{
+		{name: "default"},
+		{name: "one worker", c: HNSWConfig{Workers: 1}},
+		{name: "small", c: HNSWConfig{M: 8, EfConstruction: 50, EfSearch: 100}},
+		{name: "raw", opts: []Option{RawVectors()}},
+	}
+
+	for _, tt := range tests {
+		m, err := FromReader(bytes.NewReader(data), tt.opts...)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error from FromReader: %v", tt.name, err)
+		}
+		h := NewHNSW(m, tt.c)
+
+		r, err := CompareRankings(m, h, SampleQueries(m, 200), 10)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error from CompareRankings: %v", tt.name, err)
+		}
+		if r.Recall < 0.95 || r.ScoreError > 1e-6 {
+			t.Errorf("[%s] CompareRankings() = %v, expected recall at least 0.95, no score error", tt.name, r)
+		}
+
+		if _, err := h.CosN(Expr{"zzz": 1}, 10); err == nil {
+			t.Errorf("[%s] CosN() with unknown word returned nil error", tt.name)
+		}
+	}
+}
+
+func TestHNSWSmallM(t *testing.T) {
+	data := testRandomModelData(t, 500, 8, 1)
+	m, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	h := NewHNSW(m, HNSWConfig{M: 1})
+	if h.c.M != 2 {
+		t.Errorf("NewHNSW() with M = 1 has M = %d, expected 2", h.c.M)
+	}
+	if _, err := h.CosN(SampleQueries(m, 1)[0], 5); err != nil {
+		t.Errorf("unexpected error from CosN: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := h.Write(buf); err != nil {
+		t.Fatalf("unexpected error from Write: %v", err)
+	}
+	if _, err := ReadHNSW(bytes.NewReader(buf.Bytes()), m); err != nil {
+		t.Errorf("unexpected error from ReadHNSW: %v", err)
+	}
+}
+
+func TestHNSWWrite(t *testing.T) {
+	data := testRandomModelData(t, 500, 8, 1)
+	m, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	h := NewHNSW(m, HNSWConfig{M: 4, EfSearch: 20})
+
+	buf := &bytes.Buffer{}
+	if err := h.Write(buf); err != nil {
+		t.Fatalf("unexpected error from Write: %v", err)
+	}
+	index := buf.Bytes()
+	read, err := ReadHNSW(bytes.NewReader(index), m)
+	if err != nil {
+		t.Fatalf("unexpected error from ReadHNSW: %v", err)
+	}
+	for _, q := range SampleQueries(m, 20) {
+		want, _ := h.CosN(q, 5)
+		got, _ := read.CosN(q, 5)
+		if len(got) != len(want) {
+			t.Errorf("CosN(%v) = %v after reading index, expected %v", q, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("CosN(%v) = %v after reading index, expected %v", q, got, want)
+				break
+			}
+		}
+	}
+
+	other, err := FromReader(bytes.NewReader(testRandomModelData(t, 500, 8, 2)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	if _, err := ReadHNSW(bytes.NewReader(index), other); err == nil {
+		t.Errorf("ReadHNSW() with different model returned nil error")
+	}
+
+	bad := append([]byte("XXXX"), index[4:]...)
+	if _, err := ReadHNSW(bytes.NewReader(bad), m); err == nil {
+		t.Errorf("ReadHNSW() with bad magic returned nil error")
+	}
+	huge := append([]byte(nil), index...)
+	binary.LittleEndian.PutUint32(huge[16:], 0x7fffffff) // M
+	if _, err := ReadHNSW(bytes.NewReader(huge), m); err == nil {
+		t.Errorf("ReadHNSW() with huge M returned nil error")
+	}
+	if _, err := ReadHNSW(bytes.NewReader(index[:len(index)-10]), m); err == nil {
+		t.Errorf("ReadHNSW() with truncated data returned nil error")
+	}
+}
@@ -76,6 +76,14 @@ func (q cosNQuery) Eval(c Coser) (interface{}, error) {
 	}, nil
 }
 
+// infoer is an interface implemented by Cosers which can describe their model (see
+// Model.Info).
+type infoer interface {
+	Info() (Info, error)
+}
+
+var errNoInfo = errors.New("model description not available")
+
 // server is a type which implements http.Handler and exports endpoints
 // for performing similarity queries on a word2vec model.
 type server struct {
@@ -84,7 +92,8 @@ type server struct {
 }
 
 // NewServer creates a new word2vec server which exports endpoints for performing
-// similarity queries on a word2vec Model.
+// similarity queries on a word2vec Model.  The /info endpoint describes the model
+// (see Model.Info), if c is a Model or a cache of one (see NewCache).
 func NewServer(c Coser) http.Handler {
 	ms := &server{
 		Coser: c,
@@ -94,6 +103,7 @@ func NewServer(c Coser) http.Handler {
 	mux.HandleFunc("/cos-n", ms.handleCosNQuery)
 	mux.HandleFunc("/cos", ms.handleCosQuery)
 	mux.HandleFunc("/coses", ms.handleCosesQuery)
+	mux.HandleFunc("/info", ms.handleInfo)
 
 	ms.ServeMux = mux
 	return ms
@@ -173,6 +183,31 @@ func (s *server) handleCosNQuery(w http.ResponseWriter, r *http.Request) {
 	s.handleEval(q, w, r)
 }
 
+func (s *server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	i, ok := s.Coser.(infoer)
+	if !ok {
+		handleError(w, r, http.StatusNotFound, errNoInfo.Error())
+		return
+	}
+
+	info, err := i.Info()
+	if err != nil {
+		handleError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+
+	b, err := json.Marshal(info)
+	if err != nil {
+		msg := fmt.Sprintf("error encoding response %#v to JSON: %v", info, err)
+		handleError(w, r, http.StatusInternalServerError, msg)
+		return
+	}
+
+	if _, err := w.Write(b); err != nil {
+		log.Printf("error writing response: %v", err)
+	}
+}
+
 // Client is type which implements Coser and evaluates Expr similarity queries
 // using a word2vec Server (see above).
 type Client struct {
@@ -275,3 +310,18 @@ func (c Client) CosN(e Expr, n int) ([]Match, error) {
 	}
 	return data.Matches, nil
 }
+
+// Info returns the description of the model used by the server (see Model.Info).
+func (c Client) Info() (Info, error) {
+	body, err := c.fetch(struct{}{}, "info")
+	if err != nil {
+		return Info{}, err
+	}
+
+	var data Info
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return Info{}, fmt.Errorf("error unmarshalling result: %v", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,159 @@
+package word2vec
+
+import (
+	"math"
+	"sort"
+)
+
+// maxSweeps bounds the number of sweeps of Jacobi rotations in svd.
+const maxSweeps = 60
+
+// svd computes the thin singular value decomposition a = u diag(s) v^T of the m×n
+// matrix a (row-major, m >= n) using one-sided Jacobi rotations, which is accurate and
+// simple enough for the small dense matrices used here.  The matrix a is overwritten by
+// u (m×n, with orthonormal columns), and s (in descending order) and v (n×n, row-major)
+// are returned.  Columns of u for zero singular values are completed to an orthonormal
+// set.
+func svd(a []float64, m, n int) (s, v []float64) {
+	v = make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		v[i*n+i] = 1
+	}
+
+	for sweep := 0; sweep < maxSweeps; sweep++ {
+		rotated := false
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				var alpha, beta, gamma float64
+				for i := 0; i < m; i++ {
+					x, y := a[i*n+p], a[i*n+q]
+					alpha += x * x
+					beta += y * y
+					gamma += x * y
+				}
+				if gamma == 0 || math.Abs(gamma) <= 1e-15*math.Sqrt(alpha*beta) {
+					continue
+				}
+				rotated = true
+
+				zeta := (beta - alpha) / (2 * gamma)
+				t := math.Copysign(1, zeta) / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				c := 1 / math.Sqrt(1+t*t)
+				sn := c * t
+				rotate(a, m, n, p, q, c, sn)
+				rotate(v, n, n, p, q, c, sn)
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+
+	s = make([]float64, n)
+	for j := 0; j < n; j++ {
+		var norm float64
+		for i := 0; i < m; i++ {
+			norm += a[i*n+j] * a[i*n+j]
+		}
+		s[j] = math.Sqrt(norm)
+	}
+
+	// Sort the singular values (and the columns of u and v) in descending order.
+	order := make([]int, n)
+	for j := range order {
+		order[j] = j
+	}
+	sort.SliceStable(order, func(x, y int) bool { return s[order[x]] > s[order[y]] })
+	permuteColumns(a, m, n, order)
+	permuteColumns(v, n, n, order)
+	sorted := make([]float64, n)
+	for j, k := range order {
+		sorted[j] = s[k]
+	}
+	s = sorted
+
+	tol := 1e-12 * s[0]
+	for j := 0; j < n; j++ {
+		if s[j] > tol {
+			for i := 0; i < m; i++ {
+				a[i*n+j] /= s[j]
+			}
+		} else {
+			s[j] = 0
+			for i := 0; i < m; i++ {
+				a[i*n+j] = 0
+			}
+		}
+	}
+	completeColumns(a, m, n)
+	return s, v
+}
+
+// rotate applies the Jacobi rotation (c, s) to columns p and q of the m×n matrix a.
+func rotate(a []float64, m, n, p, q int, c, s float64) {
+	for i := 0; i < m; i++ {
+		x, y := a[i*n+p], a[i*n+q]
+		a[i*n+p] = c*x - s*y
+		a[i*n+q] = s*x + c*y
+	}
+}
+
+// permuteColumns reorders the columns of the m×n matrix a so that column j is the
+// previous column order[j].
+func permuteColumns(a []float64, m, n int, order []int) {
+	row := make([]float64, n)
+	for i := 0; i < m; i++ {
+		for j, k := range order {
+			row[j] = a[i*n+k]
+		}
+		copy(a[i*n:(i+1)*n], row)
+	}
+}
+
+// completeColumns replaces the zero columns of the m×n matrix a, whose other columns are
+// orthonormal, with unit vectors orthogonal to the other columns (m >= n).
+func completeColumns(a []float64, m, n int) {
+	col := make([]float64, m)
+	next := 0
+	for j := 0; j < n; j++ {
+		var norm float64
+		for i := 0; i < m; i++ {
+			norm += a[i*n+j] * a[i*n+j]
+		}
+		if norm != 0 {
+			continue
+		}
+
+		// Orthogonalise standard basis vectors against the columns until one remains.
+		for ; next < m; next++ {
+			for i := range col {
+				col[i] = 0
+			}
+			col[next] = 1
+			for k := 0; k < n; k++ {
+				if k == j {
+					continue
+				}
+				var dot float64
+				for i := 0; i < m; i++ {
+					dot += a[i*n+k] * col[i]
+				}
+				for i := 0; i < m; i++ {
+					col[i] -= dot * a[i*n+k]
+				}
+			}
+			norm = 0
+			for _, x := range col {
+				norm += x * x
+			}
+			if norm > 1e-6 {
+				break
+			}
+		}
+		norm = math.Sqrt(norm)
+		for i := 0; i < m; i++ {
+			a[i*n+j] = col[i] / norm
+		}
+		next++
+	}
+}
@@ -0,0 +1,94 @@
+package word2vec
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSVD(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name string
+		m, n int
+		rank int
+	}{
+		{"square", 6, 6, 6},
+		{"tall", 10, 4, 4},
+		{"rank deficient", 6, 6, 2},
+		{"zero", 3, 3, 0},
+	}
+
+	for _, tt := range tests {
+		// a = b c for random b (m×rank) and c (rank×n).
+		a := make([]float64, tt.m*tt.n)
+		for k := 0; k < tt.rank; k++ {
+			b, c := make([]float64, tt.m), make([]float64, tt.n)
+			for i := range b {
+				b[i] = r.NormFloat64()
+			}
+			for j := range c {
+				c[j] = r.NormFloat64()
+			}
+			for i := range b {
+				for j := range c {
+					a[i*tt.n+j] += b[i] * c[j]
+				}
+			}
+		}
+		orig := append([]float64(nil), a...)
+
+		s, v := svd(a, tt.m, tt.n)
+		u := a
+		for j := 1; j < tt.n; j++ {
+			if s[j] > s[j-1] {
+				t.Errorf("[%s] singular values %v, expected descending order", tt.name, s)
+				break
+			}
+		}
+		var nonzero int
+		for _, x := range s {
+			if x > 1e-9 {
+				nonzero++
+			}
+		}
+		if nonzero != tt.rank {
+			t.Errorf("[%s] %d non-zero singular values %v, expected %d", tt.name, nonzero, s, tt.rank)
+		}
+
+		for i := 0; i < tt.m; i++ {
+			for j := 0; j < tt.n; j++ {
+				var x float64
+				for k := 0; k < tt.n; k++ {
+					x += u[i*tt.n+k] * s[k] * v[j*tt.n+k]
+				}
+				if math.Abs(x-orig[i*tt.n+j]) > 1e-9 {
+					t.Errorf("[%s] (u s v^T)[%d][%d] = %v, expected %v", tt.name, i, j, x, orig[i*tt.n+j])
+				}
+			}
+		}
+
+		// The columns of u and v are orthonormal.
+		for _, x := range []struct {
+			name string
+			a    []float64
+			m    int
+		}{{"u", u, tt.m}, {"v", v, tt.n}} {
+			for j := 0; j < tt.n; j++ {
+				for k := 0; k < tt.n; k++ {
+					var dot float64
+					for i := 0; i < x.m; i++ {
+						dot += x.a[i*tt.n+j] * x.a[i*tt.n+k]
+					}
+					expected := 0.0
+					if j == k {
+						expected = 1
+					}
+					if math.Abs(dot-expected) > 1e-9 {
+						t.Errorf("[%s] column %d . column %d of %s = %v, expected %v", tt.name, j, k, x.name, dot, expected)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package word2vec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// sniffSize is the number of bytes inspected by Load to determine the model format.
+const sniffSize = 1 << 16
+
+// Load creates a Model from the model data provided by the io.Reader, which can be in
+// either the binary (see FromReader) or text (see FromTextReader) word2vec format, or the
+// fastText binary format (see FromFastText).
+// Model data compressed with gzip, bzip2, xz or zstd is detected and decompressed.
+// Data in the mapped format (see WriteMmap) is read into memory, and opts are ignored.
+func Load(r io.Reader, opts ...Option) (*Model, error) {
+	return LoadContext(context.Background(), r, opts...)
+}
+
+// LoadContext is like Load, but stops loading the model and returns ctx.Err() if ctx is
+// done.  Entries are decoded in parallel as the model data is read, and progress can be
+// monitored using the ReportProgress Option.
+func LoadContext(ctx context.Context, r io.Reader, opts ...Option) (*Model, error) {
+	size := readerSize(r)
+	br := bufio.NewReaderSize(r, sniffSize)
+	dr, done, err := decompress(br)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if dr != br {
+		br, size = dr, -1
+	}
+	o := newOptions(append(opts[:len(opts):len(opts)], dataSize(size)))
+
+	b, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	if isMmap(b) {
+		return readMmap(br)
+	}
+	if isFastText(b) {
+		return fromFastText(ctx, br, o)
+	}
+	if isText(b) {
+		return fromText(ctx, br, o)
+	}
+	return fromBinary(ctx, br, o)
+}
+
+// isText reports whether b (the beginning of model data, at most sniffSize bytes) looks
+// like a text model.
+func isText(b []byte) bool {
+	cut := len(b) >= sniffSize
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		i = len(b)
+	}
+	_, dim, ok := parseHeader(strings.Fields(string(b[:i])))
+	if !ok {
+		// No "size dim" header: only text (GloVe) files omit it.
+		return true
+	}
+
+	// The first entry of a text model is a line of the word followed by dim
+	// numbers.  In binary models the word is followed by raw float32 values.
+	b = b[i:]
+	if len(b) > 0 {
+		b = b[1:]
+	}
+	if j := bytes.IndexByte(b, '\n'); j >= 0 {
+		b, cut = b[:j], false
+	}
+	fields := strings.Fields(string(b))
+	if cut {
+		// The line is cut off (the vectors of models with large dimensions can be longer
+		// than sniffSize), so check only the values which are fully visible.
+		if len(fields) > 0 {
+			fields = fields[:len(fields)-1]
+		}
+		if len(fields) < 2 {
+			return false
+		}
+		if n := len(fields) - 1; n < dim {
+			dim = n
+		}
+	}
+	if len(fields) < dim+1 {
+		return false
+	}
+	for _, f := range fields[len(fields)-dim:] {
+		if _, err := strconv.ParseFloat(f, 32); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package word2vec
+
+import (
+	"container/heap"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// LSHConfig is a type which configures an LSH index (see NewLSH).  Zero fields take
+// their default values.
+type LSHConfig struct {
+	// Tables is the number of hash tables.  More tables give better recall, at the cost
+	// of memory and query time.  Defaults to 8.
+	Tables int
+
+	// Bits is the number of bits of the signatures in each table, between 1 and 64.
+	// More bits give smaller buckets, and so quicker queries with lower recall.  Defaults
+	// to log2 of the number of words less 3, so that buckets hold about 8 words.
+	Bits int
+
+	// Radius is the Hamming distance from the signature of a query of the buckets which
+	// are searched in each table.  Zero only searches the bucket of the signature, and
+	// each increment searches many more buckets (Bits choose Radius of them), giving
+	// better recall at the cost of query time.
+	Radius int
+
+	// Seed seeds the random hyperplanes.
+	Seed int64
+}
+
+// LSH is a Coser which finds the nearest neighbours of expressions approximately, using
+// locality-sensitive hashing by signed random projections: the signature of a vector has
+// a bit for each of a number of random hyperplanes, which is set if the vector is on its
+// positive side, so vectors with similar directions are likely to have similar
+// signatures.  The words whose signatures are close to that of a query in any table are
+// re-ranked exactly.  Cos and Coses are computed by the model.  An LSH can be used
+// concurrently.
+type LSH struct {
+	m       *Model
+	c       LSHConfig
+	planes  []Vector             // Tables×Bits hyperplane normals
+	buckets []map[uint64][]int32 // words with each signature, for each table
+	visited sync.Pool
+}
+
+// NewLSH builds an LSH index of the words of m.  The index uses the vectors of m, so m
+// must not be closed while it is used.  Returns an error if c.Bits is not between 1 and 64.
+func NewLSH(m *Model, c LSHConfig) (*LSH, error) {
+	if c.Tables <= 0 {
+		c.Tables = 8
+	}
+	if c.Bits == 0 {
+		c.Bits = bits.Len(uint(m.vocab.size())) - 4
+		if c.Bits < 1 {
+			c.Bits = 1
+		}
+	}
+	if c.Bits < 0 || c.Bits > 64 {
+		return nil, fmt.Errorf("invalid number of bits %d: must be between 1 and 64", c.Bits)
+	}
+	if c.Radius < 0 {
+		c.Radius = 0
+	}
+
+	r := rand.New(rand.NewSource(c.Seed))
+	l := &LSH{m: m, c: c, planes: make([]Vector, c.Tables*c.Bits)}
+	for i := range l.planes {
+		l.planes[i] = make(Vector, m.dim)
+		for j := range l.planes[i] {
+			l.planes[i][j] = float32(r.NormFloat64())
+		}
+	}
+
+	size := m.vocab.size()
+	sigs := make([]uint64, size*c.Tables)
+	parallel(size, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			l.signatures(sigs[i*c.Tables:(i+1)*c.Tables], m.row(i))
+		}
+	})
+
+	// The words of each table are sorted by signature, so each bucket is a slice of them.
+	l.buckets = make([]map[uint64][]int32, c.Tables)
+	parallel(c.Tables, func(lo, hi int) {
+		for t := lo; t < hi; t++ {
+			ids := make([]int32, size)
+			for i := range ids {
+				ids[i] = int32(i)
+			}
+			sig := func(k int) uint64 { return sigs[int(ids[k])*c.Tables+t] }
+			sort.Slice(ids, func(a, b int) bool {
+				if sig(a) != sig(b) {
+					return sig(a) < sig(b)
+				}
+				return ids[a] < ids[b]
+			})
+			b := make(map[uint64][]int32)
+			for start := 0; start < size; {
+				end := start + 1
+				for end < size && sig(end) == sig(start) {
+					end++
+				}
+				b[sig(start)] = ids[start:end:end]
+				start = end
+			}
+			l.buckets[t] = b
+		}
+	})
+	l.visited.New = func() interface{} { return &visitedSet{marks: make([]uint32, size)} }
+	return l, nil
+}
+
+// signatures sets sigs to the signature of v in each table.
+func (l *LSH) signatures(sigs []uint64, v Vector) {
+	for t := range sigs {
+		var sig uint64
+		for b, p := range l.planes[t*l.c.Bits : (t+1)*l.c.Bits] {
+			if p.Dot(v) >= 0 {
+				sig |= 1 << uint(b)
+			}
+		}
+		sigs[t] = sig
+	}
+}
+
+// Cos implements Coser.
+func (l *LSH) Cos(e, f Expr) (float32, error) {
+	return l.m.Cos(e, f)
+}
+
+// Coses implements Coser.
+func (l *LSH) Coses(pairs [][2]Expr) ([]float32, error) {
+	return l.m.Coses(pairs)
+}
+
+// CosN implements Coser.  The matches are scored as by Model.CosN, but only words whose
+// signatures are within c.Radius of that of the expression in some table are
+// considered, so they may not be the n most similar words, and there may be fewer than
+// n of them.
+func (l *LSH) CosN(e Expr, n int) ([]Match, error) {
+	if n == 0 {
+		return nil, nil
+	}
+
+	v, err := e.Eval(l.m)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.m.sim == Cosine {
+		v.Normalise()
+	}
+	return l.search(v, n), nil
+}
+
+// Info returns the description of the model (see Model.Info).
+func (l *LSH) Info() (Info, error) {
+	return l.m.Info()
+}
+
+// search returns the n best matches for v among the candidates in the buckets, scored as
+// by Model.cosineN.
+func (l *LSH) search(v Vector, n int) []Match {
+	visited := l.visited.Get().(*visitedSet)
+	defer l.visited.Put(visited)
+	visited.clear()
+
+	sigs := make([]uint64, l.c.Tables)
+	l.signatures(sigs, v)
+	h := make(matchHeap, 0, n)
+	for t, sig := range sigs {
+		l.probe(sig, 0, l.c.Radius, func(s uint64) {
+			for _, i := range l.buckets[t][s] {
+				if visited.visit(i) {
+					continue
+				}
+				score := l.m.store.dot(v, int(i)) * l.m.scale(int(i))
+				if len(h) < n {
+					heap.Push(&h, Match{l.m.vocab.word(int(i)), score})
+				} else if score > h[0].Score {
+					h[0] = Match{l.m.vocab.word(int(i)), score}
+					heap.Fix(&h, 0)
+				}
+			}
+		})
+	}
+
+	r := make([]Match, len(h))
+	for k := len(r) - 1; k >= 0; k-- {
+		r[k] = heap.Pop(&h).(Match)
+	}
+	return r
+}
+
+// probe calls f with sig, and with each signature which differs from sig in at most
+// radius of the bits from bit first onwards.
+func (l *LSH) probe(sig uint64, first, radius int, f func(uint64)) {
+	f(sig)
+	if radius == 0 {
+		return
+	}
+	for b := first; b < l.c.Bits; b++ {
+		l.probe(sig^(1<<uint(b)), b+1, radius-1, f)
+	}
+}
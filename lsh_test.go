@@ -0,0 +1,51 @@
+package word2vec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLSH(t *testing.T) {
+	m, err := FromReader(bytes.NewReader(testRandomModelData(t, 2000, 16, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	queries := SampleQueries(m, 200)
+
+	tests := []struct {
+		name   string
+		c      LSHConfig
+		recall float64
+	}{
+		{name: "default", c: LSHConfig{}, recall: 0.5},
+		{name: "radius", c: LSHConfig{Radius: 1}, recall: 0.9},
+		{name: "tables", c: LSHConfig{Tables: 32, Radius: 1}, recall: 0.99},
+		{name: "exhaustive", c: LSHConfig{Tables: 1, Bits: 1, Radius: 1}, recall: 1},
+	}
+
+	var last float64
+	for _, tt := range tests {
+		l, err := NewLSH(m, tt.c)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error from NewLSH: %v", tt.name, err)
+		}
+		r, err := CompareRankings(m, l, queries, 10)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error from CompareRankings: %v", tt.name, err)
+		}
+		if r.Recall < tt.recall || r.Recall < last || r.ScoreError > 1e-6 {
+			t.Errorf("[%s] CompareRankings() = %v, expected recall at least %v, no score error", tt.name, r, tt.recall)
+		}
+		last = r.Recall
+
+		if _, err := l.CosN(Expr{"zzz": 1}, 10); err == nil {
+			t.Errorf("[%s] CosN() with unknown word returned nil error", tt.name)
+		}
+	}
+
+	for _, b := range []int{-1, 65} {
+		if _, err := NewLSH(m, LSHConfig{Bits: b}); err == nil {
+			t.Errorf("NewLSH() with %d bits returned nil error", b)
+		}
+	}
+}
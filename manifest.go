@@ -0,0 +1,164 @@
+package word2vec
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+)
+
+// fingerprintChunk is the number of words hashed together when computing a fingerprint.
+const fingerprintChunk = 1 << 12
+
+// fingerprint computes the fingerprint of the words and vectors of m: the SHA-256 hash
+// (truncated to 128 bits) of the dimension, size and the hashes of each chunk of words
+// and their vectors.  Vectors are hashed normalised, so the fingerprint does not depend
+// on whether the model was loaded with RawVectors.
+func fingerprint(m *Model) [16]byte {
+	size := m.vocab.size()
+	chunks := make([][sha256.Size]byte, (size+fingerprintChunk-1)/fingerprintChunk)
+	parallel(len(chunks), func(lo, hi int) {
+		h := sha256.New()
+		buf := make([]byte, 8+4*m.dim)
+		for c := lo; c < hi; c++ {
+			h.Reset()
+			for i := c * fingerprintChunk; i < size && i < (c+1)*fingerprintChunk; i++ {
+				w := m.vocab.word(i)
+				binary.LittleEndian.PutUint64(buf, uint64(len(w)))
+				h.Write(buf[:8])
+				h.Write([]byte(w))
+
+				v := m.row(i)
+				if m.raw {
+					v = append(Vector(nil), v...)
+					v.Normalise()
+				}
+				for j, x := range v {
+					binary.LittleEndian.PutUint32(buf[4*j:], math.Float32bits(x))
+				}
+				h.Write(buf[:4*m.dim])
+			}
+			h.Sum(chunks[c][:0])
+		}
+	})
+
+	h := sha256.New()
+	buf := make([]byte, 16)
+	binary.LittleEndian.PutUint64(buf, uint64(m.dim))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(size))
+	h.Write(buf)
+	for _, c := range chunks {
+		h.Write(c[:])
+	}
+	var fp [16]byte
+	copy(fp[:], h.Sum(nil))
+	return fp
+}
+
+// Fingerprint returns a fingerprint of the contents of the model (its words and vectors)
+// as a hex string, which identifies the model being used.  The fingerprint is computed
+// when the model is loaded (or stored in mapped model data), and changes with any
+// option which changes the words or vectors of the model, except RawVectors.
+func (m *Model) Fingerprint() string {
+	return hex.EncodeToString(m.fp[:])
+}
+
+// Manifest is a type which describes a model.  Manifests are stored as JSON in a
+// sidecar file next to the model data (see ManifestPath), which is read by Open.
+type Manifest struct {
+	Name        string `json:"name,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Corpus      string `json:"corpus,omitempty"`      // training corpus
+	Created     string `json:"created,omitempty"`     // creation date (e.g. 2016-01-02)
+	License     string `json:"license,omitempty"`     // license of the model
+	Fingerprint string `json:"fingerprint,omitempty"` // fingerprint of the model (see Model.Fingerprint)
+}
+
+// ManifestPath returns the path of the manifest for the model data at path.
+func ManifestPath(path string) string {
+	return path + ".json"
+}
+
+// ReadManifest reads the manifest at path (see ManifestPath).
+func ReadManifest(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mf := &Manifest{}
+	if err := json.Unmarshal(b, mf); err != nil {
+		return nil, fmt.Errorf("error reading manifest %v: %v", path, err)
+	}
+	return mf, nil
+}
+
+// WriteFile writes the manifest to path as JSON.
+func (mf *Manifest) WriteFile(path string) error {
+	b, err := json.MarshalIndent(mf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// openManifest sets the manifest of m to that of the model data at path, if there is
+// one.  m is closed if there is an error.
+func openManifest(m *Model, path string) (*Model, error) {
+	mf, err := ReadManifest(ManifestPath(path))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	m.manifest = mf
+	return m, nil
+}
+
+// Manifest returns the manifest of the model, or nil if it has none.  The manifest is
+// read by Open from the sidecar file next to the model data (see ManifestPath), and can
+// be set using WithManifest.  The returned Manifest must not be modified.
+func (m *Model) Manifest() *Manifest {
+	return m.manifest
+}
+
+// WithManifest returns a Model which shares the data of m, but has the manifest mf (see
+// WithSimilarity).
+func (m *Model) WithManifest(mf *Manifest) *Model {
+	c := *m
+	c.manifest = mf
+	return &c
+}
+
+// Info is a type which describes a model (see Model.Info).
+type Info struct {
+	Size        int       `json:"size"`
+	Dim         int       `json:"dim"`
+	Fingerprint string    `json:"fingerprint"`
+	Manifest    *Manifest `json:"manifest,omitempty"`
+}
+
+// Info returns a description of the model: its size, dimension, fingerprint and
+// manifest (if any).
+func (m *Model) Info() (Info, error) {
+	return Info{
+		Size:        m.Size(),
+		Dim:         m.Dim(),
+		Fingerprint: m.Fingerprint(),
+		Manifest:    m.manifest,
+	}, nil
+}
+
+// Verify returns an error if the manifest records a fingerprint which does not match
+// that of m.
+func (mf *Manifest) Verify(m *Model) error {
+	if mf.Fingerprint != "" && mf.Fingerprint != m.Fingerprint() {
+		return fmt.Errorf("model fingerprint %v does not match manifest fingerprint %v", m.Fingerprint(), mf.Fingerprint)
+	}
+	return nil
+}
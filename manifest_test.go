@@ -0,0 +1,123 @@
+package word2vec
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	data := testModelData(t, []string{"hello", "world", "again"}, []Vector{{0, 2}, {3, 4}, {1, 0}})
+	m, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	fp := m.Fingerprint()
+	if len(fp) != 32 {
+		t.Errorf("m.Fingerprint() = %q, expected 32 hex digits", fp)
+	}
+
+	text := &bytes.Buffer{}
+	if err := m.WriteText(text); err != nil {
+		t.Fatalf("unexpected error from WriteText: %v", err)
+	}
+	mmap := &bytes.Buffer{}
+	if err := m.WriteMmap(mmap); err != nil {
+		t.Fatalf("unexpected error from WriteMmap: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		opts []Option
+		same bool
+	}{
+		{"raw", data, []Option{RawVectors()}, true},
+		{"text", text.Bytes(), nil, true},
+		{"mmap", mmap.Bytes(), nil, true},
+		{"max size", data, []Option{MaxSize(2)}, false},
+		{"vectors", testModelData(t, []string{"hello", "world", "again"}, []Vector{{0, 2}, {3, 4}, {1, 1}}), nil, false},
+		{"words", testModelData(t, []string{"hello", "world", "Again"}, []Vector{{0, 2}, {3, 4}, {1, 0}}), nil, false},
+	}
+
+	for _, tt := range tests {
+		m, err := Load(bytes.NewReader(tt.data), tt.opts...)
+		if err != nil {
+			t.Errorf("[%s] unexpected error from Load: %v", tt.name, err)
+			continue
+		}
+		if got := m.Fingerprint(); (got == fp) != tt.same {
+			t.Errorf("[%s] m.Fingerprint() = %v, expected same as %v: %v", tt.name, got, fp, tt.same)
+		}
+	}
+
+	if got := m.Convert(Int8Storage).Fingerprint(); got == fp {
+		t.Errorf("m.Convert(Int8Storage).Fingerprint() = %v, expected different", got)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	data := testModelData(t, []string{"hello", "world"}, []Vector{{1, 2}, {2, 1}})
+	path := filepath.Join(t.TempDir(), "model.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("unexpected error writing model: %v", err)
+	}
+
+	m, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error from Open: %v", err)
+	}
+	if m.Manifest() != nil {
+		t.Errorf("m.Manifest() = %v, expected nil", m.Manifest())
+	}
+
+	mf := &Manifest{
+		Name:        "test",
+		Version:     "1.0",
+		Corpus:      "hello world",
+		Created:     "2016-01-02",
+		License:     "CC0",
+		Fingerprint: m.Fingerprint(),
+	}
+	if err := mf.WriteFile(ManifestPath(path)); err != nil {
+		t.Fatalf("unexpected error from WriteFile: %v", err)
+	}
+
+	m, err = Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error from Open: %v", err)
+	}
+	if !reflect.DeepEqual(m.Manifest(), mf) {
+		t.Errorf("m.Manifest() = %v, expected %v", m.Manifest(), mf)
+	}
+	if err := m.Manifest().Verify(m); err != nil {
+		t.Errorf("unexpected error from Verify: %v", err)
+	}
+	if err := m.Manifest().Verify(m.Convert(Float16Storage)); err == nil {
+		t.Errorf("expected error from Verify for converted model")
+	}
+
+	s := httptest.NewServer(NewServer(NewCache(m)))
+	defer s.Close()
+	c := Client{Addr: strings.TrimPrefix(s.URL, "http://")}
+
+	info, err := c.Info()
+	if err != nil {
+		t.Fatalf("unexpected error from Info: %v", err)
+	}
+	expected := Info{Size: 2, Dim: 2, Fingerprint: m.Fingerprint(), Manifest: mf}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("c.Info() = %+v, expected %+v", info, expected)
+	}
+
+	if err := os.WriteFile(ManifestPath(path), []byte("{"), 0644); err != nil {
+		t.Fatalf("unexpected error writing manifest: %v", err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Errorf("expected error from Open with invalid manifest")
+	}
+}
@@ -0,0 +1,523 @@
+package word2vec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"unsafe"
+)
+
+// The mapped model format is designed to be memory-mapped and queried in place, so
+// that models can be opened without parsing or copying vector data.  All integers are
+// little-endian, and the file is laid out as follows:
+//
+//	header    (mmapHeaderSize bytes, see mmapHeader)
+//	offsets   (size+1) x uint64: offset of each word in the strings section
+//	index     size x uint32: word indices, sorted by word
+//	strings   concatenated words
+//	vectors   size x dim float32, float16 or int8, or size x subspaces product quantisation
+//	          codes (see Storage), starting at a multiple of mmapAlign
+//	norms     size x float32 (if mmapFlagNorms is set), starting at a multiple of 4
+//	scales    size x float32 (if mmapFlagInt8 is set), starting at a multiple of 4
+//	codebooks centroids x dim float32 (if mmapFlagPQ is set), starting at a multiple of 4
+//
+// Words, vectors, norms and scales are stored in vocabulary order.  The codebooks hold
+// the centroids of each sub-space in turn (see pqStorage).
+const (
+	mmapMagic      = "W2VM"
+	mmapVersion    = 1
+	mmapHeaderSize = 128
+	mmapAlign      = 64
+)
+
+// Flags set in mmapHeader.
+const (
+	mmapFlagRaw     = 1 << iota // vectors are not normalised
+	mmapFlagNorms               // norms section is present
+	mmapFlagFloat16             // vectors are stored as float16
+	mmapFlagInt8                // vectors are stored as int8, and scales section is present
+	mmapFlagPQ                  // vectors are stored as codes, and codebooks section is present
+
+	mmapFlags        = mmapFlagRaw | mmapFlagNorms | mmapFlagFloat16 | mmapFlagInt8 | mmapFlagPQ
+	mmapStorageFlags = mmapFlagFloat16 | mmapFlagInt8 | mmapFlagPQ
+)
+
+// mmapHeader is the header of a mapped model file.  Offsets are from the start of
+// the file.
+type mmapHeader struct {
+	Magic       [4]byte
+	Version     uint32
+	Size        uint64
+	Dim         uint64
+	Offsets     uint64
+	Index       uint64
+	Strings     uint64
+	Vectors     uint64
+	End         uint64
+	Flags       uint64
+	Norms       uint64
+	Scales      uint64
+	Codebooks   uint64
+	Subspaces   uint32 // product quantisation sub-spaces and centroids (see PQConfig)
+	Centroids   uint32
+	Fingerprint [16]byte // see Model.Fingerprint
+	Reserved    [1]uint64
+}
+
+// layout computes the section offsets for a model with the given size, dim, total
+// length of words and flags.  For product quantisation, h.Subspaces and h.Centroids
+// must already be set.
+func (h *mmapHeader) layout(size, dim, strLen, flags uint64) {
+	copy(h.Magic[:], mmapMagic)
+	h.Version = mmapVersion
+	h.Size = size
+	h.Dim = dim
+	h.Flags = flags
+	h.Offsets = mmapHeaderSize
+	h.Index = h.Offsets + 8*(size+1)
+	h.Strings = h.Index + 4*size
+	h.Vectors = align(h.Strings+strLen, mmapAlign)
+	h.End = h.Vectors + h.vectorsSize()
+	h.Norms, h.Scales, h.Codebooks = 0, 0, 0
+	if flags&mmapFlagNorms != 0 {
+		h.Norms = align(h.End, 4)
+		h.End = h.Norms + 4*size
+	}
+	if flags&mmapFlagInt8 != 0 {
+		h.Scales = align(h.End, 4)
+		h.End = h.Scales + 4*size
+	}
+	if flags&mmapFlagPQ != 0 {
+		h.Codebooks = align(h.End, 4)
+		h.End = h.Codebooks + 4*uint64(h.Centroids)*dim
+	}
+}
+
+// vectorsSize returns the size in bytes of the vectors section.
+func (h *mmapHeader) vectorsSize() uint64 {
+	switch {
+	case h.Flags&mmapFlagFloat16 != 0:
+		return 2 * h.Size * h.Dim
+	case h.Flags&mmapFlagInt8 != 0:
+		return h.Size * h.Dim
+	case h.Flags&mmapFlagPQ != 0:
+		return h.Size * uint64(h.Subspaces)
+	}
+	return 4 * h.Size * h.Dim
+}
+
+func align(n, a uint64) uint64 {
+	return (n + a - 1) / a * a
+}
+
+// WriteMmap writes the model to w in the mapped model format, which can be opened
+// using OpenMmap.
+func (m *Model) WriteMmap(w io.Writer) error {
+	size := m.Size()
+
+	var strLen uint64
+	index := make([]uint32, size)
+	for i := range index {
+		index[i] = uint32(i)
+		strLen += uint64(len(m.vocab.word(i)))
+	}
+	sort.Slice(index, func(i, j int) bool {
+		return m.vocab.word(int(index[i])) < m.vocab.word(int(index[j]))
+	})
+
+	var flags uint64
+	if m.raw {
+		flags |= mmapFlagRaw
+	}
+	if m.norms != nil {
+		flags |= mmapFlagNorms
+	}
+	h := mmapHeader{Fingerprint: m.fp}
+	switch st := m.store.(type) {
+	case *float16Storage:
+		flags |= mmapFlagFloat16
+	case *int8Storage:
+		flags |= mmapFlagInt8
+	case *pqStorage:
+		flags |= mmapFlagPQ
+		h.Subspaces, h.Centroids = uint32(st.m), uint32(st.k)
+	}
+	h.layout(uint64(size), uint64(m.dim), strLen, flags)
+
+	bw := bufio.NewWriter(w)
+	if err := binary.Write(bw, binary.LittleEndian, &h); err != nil {
+		return err
+	}
+
+	var off uint64
+	buf := make([]byte, 8)
+	for i := 0; i <= size; i++ {
+		binary.LittleEndian.PutUint64(buf, off)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+		if i < size {
+			off += uint64(len(m.vocab.word(i)))
+		}
+	}
+
+	if err := binary.Write(bw, binary.LittleEndian, index); err != nil {
+		return err
+	}
+
+	for i := 0; i < size; i++ {
+		if _, err := bw.WriteString(m.vocab.word(i)); err != nil {
+			return err
+		}
+	}
+
+	pad := make([]byte, h.Vectors-h.Strings-strLen)
+	if _, err := bw.Write(pad); err != nil {
+		return err
+	}
+
+	if err := writeMmapVectors(bw, m); err != nil {
+		return err
+	}
+
+	end := h.Vectors + h.vectorsSize()
+	if m.norms != nil {
+		if _, err := bw.Write(make([]byte, h.Norms-end)); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, binary.LittleEndian, m.norms); err != nil {
+			return err
+		}
+		end = h.Norms + 4*uint64(size)
+	}
+	if st, ok := m.store.(*int8Storage); ok {
+		if _, err := bw.Write(make([]byte, h.Scales-end)); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, binary.LittleEndian, st.scales); err != nil {
+			return err
+		}
+		end = h.Scales + 4*uint64(size)
+	}
+	if st, ok := m.store.(*pqStorage); ok {
+		if _, err := bw.Write(make([]byte, h.Codebooks-end)); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, binary.LittleEndian, st.codebooks); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeMmapVectors writes the vectors of m to w as stored.
+func writeMmapVectors(w io.Writer, m *Model) error {
+	switch st := m.store.(type) {
+	case *float16Storage:
+		buf := make([]byte, 2*m.dim)
+		for i := 0; i < m.Size(); i++ {
+			for j, x := range st.vecs[i*m.dim : (i+1)*m.dim] {
+				binary.LittleEndian.PutUint16(buf[2*j:], uint16(x))
+			}
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *int8Storage:
+		return binary.Write(w, binary.LittleEndian, st.vecs)
+
+	case *pqStorage:
+		_, err := w.Write(st.codes)
+		return err
+	}
+
+	for i := 0; i < m.Size(); i++ {
+		if err := binary.Write(w, binary.LittleEndian, m.row(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isMmap reports whether b (the beginning of model data) is in the mapped model format.
+func isMmap(b []byte) bool {
+	return len(b) >= len(mmapMagic) && string(b[:len(mmapMagic)]) == mmapMagic
+}
+
+// Open creates a Model from the model file at path.  Files in the mapped model format
+// (see WriteMmap) are opened using OpenMmap (and opts are ignored), otherwise the format
+// and compression are detected as in Load.  The manifest of the model is read from the
+// sidecar file next to it, if there is one (see ManifestPath).  The returned Model should
+// be closed when it is no longer needed.
+func Open(path string, opts ...Option) (*Model, error) {
+	return OpenContext(context.Background(), path, opts...)
+}
+
+// OpenContext is like Open, but stops loading the model if ctx is done (see LoadContext).
+func OpenContext(ctx context.Context, path string, opts ...Option) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := make([]byte, len(mmapMagic))
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	if isMmap(b[:n]) {
+		return OpenMmap(path)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	m, err := LoadContext(ctx, f, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return openManifest(m, path)
+}
+
+// OpenMmap creates a Model from the mapped model file at path (see WriteMmap).  The
+// file is memory-mapped (where supported) and queried in place, so opening is fast
+// regardless of the size of the model, and the data is shared with other processes
+// using the same file.  The manifest is read as in Open.  The returned Model must be
+// closed when it is no longer needed.
+func OpenMmap(path string) (*Model, error) {
+	if !littleEndian {
+		return nil, errors.New("mapped models are only supported on little-endian architectures")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := mapFile(f, fi.Size())
+	if err != nil {
+		return nil, fmt.Errorf("error mapping model data file: %v", err)
+	}
+
+	m, err := fromMmap(data)
+	if err != nil {
+		unmapFile(data)
+		return nil, err
+	}
+	m.closer = &mapping{data: data}
+	return openManifest(m, path)
+}
+
+// readMmap creates a Model from mapped model data read from r into memory.
+func readMmap(r io.Reader) (*Model, error) {
+	if !littleEndian {
+		return nil, errors.New("mapped models are only supported on little-endian architectures")
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return fromMmap(data)
+	}
+
+	// Copy into a []uint64 so that the data is suitably aligned.
+	buf := make([]uint64, (len(data)+7)/8)
+	b := unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), 8*len(buf))[:len(data)]
+	copy(b, data)
+	return fromMmap(b)
+}
+
+// fromMmap creates a Model which references the mapped model data in b.
+func fromMmap(b []byte) (*Model, error) {
+	if len(b) < mmapHeaderSize {
+		return nil, fmt.Errorf("mapped model data too short: %d bytes", len(b))
+	}
+
+	var h mmapHeader
+	if err := binary.Read(bytes.NewReader(b[:mmapHeaderSize]), binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	if string(h.Magic[:]) != mmapMagic {
+		return nil, errors.New("invalid mapped model data: bad magic")
+	}
+	if h.Version != mmapVersion {
+		return nil, fmt.Errorf("unsupported mapped model version: %d", h.Version)
+	}
+
+	// Recompute the layout from the header sizes rather than trusting the offsets,
+	// guarding against overflow.
+	if h.Size > 1<<32 || h.Dim == 0 || h.Dim > 1<<20 || h.Vectors < h.Strings || h.Vectors > uint64(len(b)) {
+		return nil, fmt.Errorf("invalid mapped model data: size %d, dim %d", h.Size, h.Dim)
+	}
+	if st := h.Flags & mmapStorageFlags; h.Flags&^mmapFlags != 0 || st&(st-1) != 0 {
+		return nil, fmt.Errorf("invalid mapped model data: unknown flags %#x", h.Flags)
+	}
+	if h.Flags&mmapFlagPQ != 0 && (h.Subspaces == 0 || uint64(h.Subspaces) > h.Dim || h.Centroids == 0 || h.Centroids > 256) {
+		return nil, fmt.Errorf("invalid mapped model data: %d sub-spaces, %d centroids", h.Subspaces, h.Centroids)
+	}
+	want := mmapHeader{Flags: h.Flags, Subspaces: h.Subspaces, Centroids: h.Centroids}
+	want.layout(h.Size, h.Dim, h.Vectors-h.Strings, h.Flags)
+	if want.Offsets != h.Offsets || want.Index != h.Index || want.Strings != h.Strings ||
+		want.Vectors != h.Vectors || want.Norms != h.Norms || want.Scales != h.Scales ||
+		want.Codebooks != h.Codebooks || want.End != h.End {
+		return nil, errors.New("invalid mapped model data: inconsistent section offsets")
+	}
+	if h.End > uint64(len(b)) {
+		return nil, fmt.Errorf("mapped model data truncated: expected %d bytes, got %d", h.End, len(b))
+	}
+
+	size := int(h.Size)
+	v := &mmapVocab{
+		offsets: uint64s(b[h.Offsets:h.Index]),
+		index:   uint32s(b[h.Index:h.Strings]),
+		strings: b[h.Strings:h.Vectors],
+	}
+	for i := 0; i < size; i++ {
+		if v.offsets[i] > v.offsets[i+1] {
+			return nil, fmt.Errorf("invalid mapped model data: bad offset for word %d", i)
+		}
+	}
+	if v.offsets[size] > uint64(len(v.strings)) {
+		return nil, errors.New("invalid mapped model data: word offsets out of range")
+	}
+	for i, j := range v.index {
+		if int(j) >= size {
+			return nil, fmt.Errorf("invalid mapped model data: bad index entry %d", i)
+		}
+	}
+
+	dim := int(h.Dim)
+	vecs := b[h.Vectors : h.Vectors+h.vectorsSize()]
+	m := &Model{
+		dim:   dim,
+		vocab: v,
+		raw:   h.Flags&mmapFlagRaw != 0,
+	}
+	switch {
+	case h.Flags&mmapFlagFloat16 != 0:
+		m.store = &float16Storage{dim: dim, vecs: float16s(vecs)}
+	case h.Flags&mmapFlagInt8 != 0:
+		m.store = &int8Storage{dim: dim, vecs: int8s(vecs), scales: float32s(b[h.Scales : h.Scales+4*h.Size])}
+	case h.Flags&mmapFlagPQ != 0:
+		st := newPQStorage(dim, int(h.Subspaces), int(h.Centroids))
+		st.codebooks = float32s(b[h.Codebooks:h.End])
+		st.codes = vecs
+		if st.k < 256 {
+			for i, c := range st.codes {
+				if int(c) >= st.k {
+					return nil, fmt.Errorf("invalid mapped model data: bad code for word %d", i/st.m)
+				}
+			}
+		}
+		m.store = st
+	default:
+		m.store = &float32Storage{dim: dim, vecs: float32s(vecs)}
+	}
+	if h.Flags&mmapFlagNorms != 0 {
+		m.norms = float32s(b[h.Norms : h.Norms+4*h.Size])
+	}
+	m.fp = h.Fingerprint
+	if m.fp == ([16]byte{}) {
+		m.fp = fingerprint(m)
+	}
+	return m, nil
+}
+
+// mmapVocab is a vocabulary which references mapped model data.
+type mmapVocab struct {
+	offsets []uint64
+	index   []uint32
+	strings []byte
+}
+
+func (v *mmapVocab) bytes(i int) []byte {
+	return v.strings[v.offsets[i]:v.offsets[i+1]]
+}
+
+func (v *mmapVocab) id(w string) (int, bool) {
+	n := len(v.index)
+	j := sort.Search(n, func(j int) bool {
+		return string(v.bytes(int(v.index[j]))) >= w
+	})
+	if j < n && string(v.bytes(int(v.index[j]))) == w {
+		return int(v.index[j]), true
+	}
+	return 0, false
+}
+
+func (v *mmapVocab) word(i int) string { return string(v.bytes(i)) }
+func (v *mmapVocab) size() int         { return len(v.index) }
+
+// mapping is an io.Closer which unmaps mapped model data.
+type mapping struct {
+	data []byte
+}
+
+func (m *mapping) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	err := unmapFile(m.data)
+	m.data = nil
+	return err
+}
+
+// littleEndian is true if the native byte order is little-endian, in which case
+// mapped model data can be referenced in place.
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+func uint64s(b []byte) []uint64 {
+	if len(b) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*uint64)(unsafe.Pointer(&b[0])), len(b)/8)
+}
+
+func uint32s(b []byte) []uint32 {
+	if len(b) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*uint32)(unsafe.Pointer(&b[0])), len(b)/4)
+}
+
+func float16s(b []byte) []Float16 {
+	if len(b) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*Float16)(unsafe.Pointer(&b[0])), len(b)/2)
+}
+
+func int8s(b []byte) []int8 {
+	if len(b) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*int8)(unsafe.Pointer(&b[0])), len(b))
+}
+
+func float32s(b []byte) []float32 {
+	if len(b) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*float32)(unsafe.Pointer(&b[0])), len(b)/4)
+}
@@ -0,0 +1,30 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package word2vec
+
+import (
+	"io"
+	"os"
+	"unsafe"
+)
+
+// mapFile reads the first size bytes of f into memory, as memory-mapping is not
+// supported on this platform.
+func mapFile(f *os.File, size int64) ([]byte, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	// Allocate as []uint64 so that the data is suitably aligned.
+	buf := make([]uint64, (size+7)/8)
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), 8*len(buf))[:size]
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// unmapFile releases data returned by mapFile.
+func unmapFile(data []byte) error {
+	return nil
+}
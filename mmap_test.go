@@ -0,0 +1,112 @@
+package word2vec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMmap(t *testing.T) {
+	words := []string{"hello", "world", "a", "zebra"}
+	data := testModelData(t, words, []Vector{{0, 1}, {1, 0}, {0.6, 0.8}, {-1, 0}})
+
+	m, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "model.w2vm")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+	if err := m.WriteMmap(f); err != nil {
+		t.Fatalf("unexpected error from WriteMmap: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("unexpected error closing file: %v", err)
+	}
+
+	mm, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error from Open: %v", err)
+	}
+	defer mm.Close()
+
+	if _, ok := mm.vocab.(*mmapVocab); !ok {
+		t.Errorf("Open() returned model with vocabulary %T, expected *mmapVocab", mm.vocab)
+	}
+	if mm.Size() != len(words) {
+		t.Errorf("mm.Size() = %d, expected %d", mm.Size(), len(words))
+	}
+	if mm.Dim() != 2 {
+		t.Errorf("mm.Dim() = %d, expected 2", mm.Dim())
+	}
+
+	for _, w := range words {
+		n, _ := m.Norm(w)
+		nm, err := mm.Norm(w)
+		if err != nil {
+			t.Errorf("unexpected error from mm.Norm(%q): %v", w, err)
+		}
+		if n != nm {
+			t.Errorf("mm.Norm(%q) = %v, expected %v", w, nm, n)
+		}
+	}
+
+	query := append(words, "missing")
+	if !reflect.DeepEqual(mm.Map(query), m.Map(query)) {
+		t.Errorf("mm.Map() = %v, expected %v", mm.Map(query), m.Map(query))
+	}
+
+	x := Expr{"hello": 1.0}
+	matches, err := mm.CosN(x, 2)
+	if err != nil {
+		t.Fatalf("unexpected error from mm.CosN(x, 2): %v", err)
+	}
+	expected, err := m.CosN(x, 2)
+	if err != nil {
+		t.Fatalf("unexpected error from m.CosN(x, 2): %v", err)
+	}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("mm.CosN(x, 2) = %v, expected %v", matches, expected)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := mm.WriteBinary(buf); err != nil {
+		t.Fatalf("unexpected error from WriteBinary: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("WriteBinary() = %q, expected %q", buf.Bytes(), data)
+	}
+}
+
+func TestFromMmapErrors(t *testing.T) {
+	m, err := FromReader(bytes.NewReader(testModelData(t, []string{"hello", "world"}, []Vector{{0, 1}, {1, 0}})))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := m.WriteMmap(buf); err != nil {
+		t.Fatalf("unexpected error from WriteMmap: %v", err)
+	}
+	data := buf.Bytes()
+
+	if _, err := fromMmap(data); err != nil {
+		t.Fatalf("unexpected error from fromMmap: %v", err)
+	}
+
+	for n := 0; n < len(data); n++ {
+		if _, err := fromMmap(data[:n]); err == nil {
+			t.Errorf("expected error from fromMmap with %d of %d bytes", n, len(data))
+		}
+	}
+
+	bad := append([]byte(nil), data...)
+	bad[mmapHeaderSize+8] = 0xff // end offset of the first word
+	if _, err := fromMmap(bad); err == nil {
+		t.Errorf("expected error from fromMmap with bad word offset")
+	}
+}
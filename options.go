@@ -0,0 +1,119 @@
+package word2vec
+
+import "strings"
+
+// Option is a type which configures how model data is loaded.  Options do not apply
+// to models in the mapped format, which are opened as they were written (see WriteMmap).
+type Option func(*options)
+
+type options struct {
+	raw     bool
+	maxSize int
+	keep    func(string) bool
+	fold    func(string) string
+	merge   MergePolicy
+
+	limits          Limits
+	allowDuplicates bool
+	dataSize        int64
+	subwords        bool
+	storage         Storage
+	pq              PQConfig
+	postProcess     bool
+	abtt            int // number of components removed by AllButTheTop
+
+	progress func(Progress)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		limits:   DefaultLimits,
+		dataSize: -1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// RawVectors is an Option which keeps the vectors as they appear in the model data,
+// rather than normalising them.  Vector norms are always retained (see Model.Norm), so
+// this only affects the vectors returned by methods such as Model.Map and the data
+// written out by Model.WriteBinary.
+func RawVectors() Option {
+	return func(o *options) {
+		o.raw = true
+	}
+}
+
+// MaxSize is an Option which limits the model to the first n words in the model data
+// (after applying Filter and Fold).  As word2vec models are usually sorted by descending
+// frequency, this loads the n most frequent words.  Reading stops once n words have been
+// loaded.
+func MaxSize(n int) Option {
+	return func(o *options) {
+		o.maxSize = n
+	}
+}
+
+// Filter is an Option which only loads words for which keep returns true.  If Fold is
+// also used, keep is called with the folded word.
+func Filter(keep func(w string) bool) Option {
+	return func(o *options) {
+		o.keep = keep
+	}
+}
+
+// Fold is an Option which replaces each word in the model data with fold(w), for instance
+// strings.ToLower for a lower-cased vocabulary, or a Unicode normalisation function.
+// Words which fold to the same string are combined according to the MergePolicy (see
+// Merge).
+func Fold(fold func(w string) string) Option {
+	return func(o *options) {
+		o.fold = fold
+	}
+}
+
+// FoldCase is an Option which lower-cases words in the model data (see Fold).
+func FoldCase() Option {
+	return Fold(strings.ToLower)
+}
+
+// MergePolicy is a type which determines how entries in model data with the same word
+// (after folding, see Fold) are combined.
+type MergePolicy int
+
+// Merge policies.
+const (
+	// MergeFirst keeps the vector of the first entry for a word, which is the most
+	// frequent in models sorted by frequency.  This is the default.
+	MergeFirst MergePolicy = iota
+
+	// MergeAverage averages the vectors of all the entries for a word.  The word keeps
+	// the position of its first entry.
+	MergeAverage
+)
+
+// Merge is an Option which sets the policy used to combine entries for the same word.
+func Merge(p MergePolicy) Option {
+	return func(o *options) {
+		o.merge = p
+	}
+}
+
+// Progress is a type which describes how much model data has been loaded (see
+// ReportProgress).
+type Progress struct {
+	Entries    int   // number of entries read
+	Size       int   // number of entries declared in the header, or -1 if not known
+	Bytes      int64 // number of bytes of (decompressed) model data read
+	TotalBytes int64 // number of bytes of model data, or -1 if not known (e.g. compressed data)
+}
+
+// ReportProgress is an Option which calls f periodically as entries are read from
+// model data.  Calls are made from the loading goroutine, so f should return quickly.
+func ReportProgress(f func(Progress)) Option {
+	return func(o *options) {
+		o.progress = f
+	}
+}
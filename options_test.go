@@ -0,0 +1,100 @@
+package word2vec
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestLoadOptions(t *testing.T) {
+	words := []string{"the", "The", "of", "Of", "42", "and"}
+	vecs := []Vector{{1, 0}, {0, 1}, {0, 2}, {0, 4}, {1, 1}, {3, 4}}
+	data := testModelData(t, words, vecs)
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		words []string
+		vecs  map[string]Vector
+	}{
+		{
+			name:  "none",
+			words: words,
+		},
+		{
+			name:  "max size",
+			opts:  []Option{MaxSize(3)},
+			words: []string{"the", "The", "of"},
+		},
+		{
+			name: "filter",
+			opts: []Option{Filter(func(w string) bool {
+				return strings.IndexFunc(w, unicode.IsDigit) < 0
+			})},
+			words: []string{"the", "The", "of", "Of", "and"},
+		},
+		{
+			name:  "fold",
+			opts:  []Option{FoldCase()},
+			words: []string{"the", "of", "42", "and"},
+			vecs: map[string]Vector{
+				"the": {1, 0},
+				"of":  {0, 2},
+			},
+		},
+		{
+			name:  "fold average",
+			opts:  []Option{FoldCase(), Merge(MergeAverage)},
+			words: []string{"the", "of", "42", "and"},
+			vecs: map[string]Vector{
+				"the": {0.5, 0.5},
+				"of":  {0, 3},
+			},
+		},
+		{
+			name:  "fold max size",
+			opts:  []Option{FoldCase(), MaxSize(2)},
+			words: []string{"the", "of"},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, load := range []func() (*Model, error){
+			func() (*Model, error) { return FromReader(bytes.NewReader(data), append(tt.opts, RawVectors())...) },
+			func() (*Model, error) {
+				m, err := FromReader(bytes.NewReader(data), RawVectors())
+				if err != nil {
+					return nil, err
+				}
+				buf := &bytes.Buffer{}
+				if err := m.WriteText(buf); err != nil {
+					return nil, err
+				}
+				return FromTextReader(buf, append(tt.opts, RawVectors())...)
+			},
+		} {
+			m, err := load()
+			if err != nil {
+				t.Errorf("[%s] unexpected error loading model: %v", tt.name, err)
+				continue
+			}
+
+			var got []string
+			m.Range(func(id int, w string, v Vector) bool {
+				got = append(got, w)
+				return true
+			})
+			if !reflect.DeepEqual(got, tt.words) {
+				t.Errorf("[%s] words = %v, expected %v", tt.name, got, tt.words)
+			}
+
+			for w, v := range tt.vecs {
+				if u := m.Map([]string{w})[w]; !reflect.DeepEqual(u, v) {
+					t.Errorf("[%s] vector for %q = %v, expected %v", tt.name, w, u, v)
+				}
+			}
+		}
+	}
+}
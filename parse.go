@@ -0,0 +1,293 @@
+package word2vec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseError is an error returned when model data cannot be parsed.
+type ParseError struct {
+	Entry  int   // index of the entry being parsed, or -1 for the header
+	Offset int64 // byte offset in the (decompressed) model data
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.Entry < 0 {
+		return fmt.Sprintf("error parsing model header at offset %d: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("error parsing model entry %d at offset %d: %v", e.Entry, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Limits is a type which defines limits on model data, beyond which it is rejected
+// rather than loaded.
+type Limits struct {
+	Size       int // maximum number of entries declared in the header
+	Dim        int // maximum vector dimension
+	WordLength int // maximum length of a word in bytes
+}
+
+// DefaultLimits are the Limits used unless the WithLimits Option is given.
+var DefaultLimits = Limits{
+	Size:       1 << 28,
+	Dim:        1 << 14,
+	WordLength: 1 << 12,
+}
+
+// WithLimits is an Option which sets the limits on model data (see DefaultLimits).
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+// AllowDuplicates is an Option which accepts model data containing the same word more
+// than once, combining the entries according to the MergePolicy (see Merge).  By default
+// duplicate words are an error, unless Fold is used.
+func AllowDuplicates() Option {
+	return func(o *options) {
+		o.allowDuplicates = true
+	}
+}
+
+// dataSize is an Option which records the number of bytes of model data available, if
+// known, which bounds the memory allocated before the data is read.
+func dataSize(n int64) Option {
+	return func(o *options) {
+		o.dataSize = n
+	}
+}
+
+// readerSize returns the number of bytes remaining in r, or -1 if it is not known.
+func readerSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return -1
+		}
+		off, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return fi.Size() - off
+	}
+	return -1
+}
+
+// maxPrealloc is the maximum number of vector components allocated up front when the
+// amount of model data is not known.
+const maxPrealloc = 1 << 22
+
+var (
+	errDuplicateWord = errors.New("duplicate word")
+	errEmptyWord     = errors.New("empty word")
+	errNonFinite     = errors.New("non-finite vector value")
+)
+
+// parser reads model data, tracking the position for errors.
+type parser struct {
+	br    *bufio.Reader
+	o     *options
+	off   int64 // offset of the next byte to be read
+	start int64 // offset of the current entry
+	entry int   // current entry, -1 for the header
+	buf   []byte
+}
+
+func newParser(r io.Reader, o *options) *parser {
+	if o.dataSize < 0 {
+		o.dataSize = readerSize(r)
+	}
+	return &parser{
+		br:    bufio.NewReaderSize(r, sniffSize),
+		o:     o,
+		entry: -1,
+	}
+}
+
+// next marks the start of the next entry.
+func (p *parser) next() {
+	p.entry++
+	p.start = p.off
+}
+
+// error returns a ParseError for the current entry.
+func (p *parser) error(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &ParseError{Entry: p.entry, Offset: p.start, Err: err}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return p.error(fmt.Errorf(format, args...))
+}
+
+// checkHeader validates the size and dimension declared in a header.
+func (p *parser) checkHeader(size, dim int) error {
+	if size < 0 || size > p.o.limits.Size {
+		return p.errorf("invalid size %d (limit %d)", size, p.o.limits.Size)
+	}
+	if dim <= 0 || dim > p.o.limits.Dim {
+		return p.errorf("invalid dimension %d (limit %d)", dim, p.o.limits.Dim)
+	}
+	return nil
+}
+
+// prealloc returns the number of entries to allocate up front for model data declaring
+// size entries, where each entry is at least entryLen bytes.
+func (p *parser) prealloc(size, dim, entryLen int) int {
+	n := size
+	if p.o.maxSize > 0 && n > p.o.maxSize {
+		n = p.o.maxSize
+	}
+	if p.o.dataSize >= 0 {
+		if m := p.o.dataSize / int64(entryLen); int64(n) > m {
+			n = int(m)
+		}
+	} else if n > maxPrealloc/dim {
+		n = maxPrealloc / dim
+	}
+	return n
+}
+
+// line reads the next non-empty line, without the trailing newline.  Returns io.EOF when
+// there are no more lines.  Lines longer than max bytes are an error.
+func (p *parser) line(max int) (string, error) {
+	for {
+		b, err := p.br.ReadSlice('\n')
+		p.off += int64(len(b))
+		if err == bufio.ErrBufferFull {
+			// Line is longer than the buffer: accumulate it.
+			buf := append(p.buf[:0], b...)
+			for err == bufio.ErrBufferFull && len(buf) <= max {
+				b, err = p.br.ReadSlice('\n')
+				p.off += int64(len(b))
+				buf = append(buf, b...)
+			}
+			p.buf = buf
+			b = buf
+		}
+		if len(b) > max {
+			return "", p.errorf("line too long (limit %d bytes)", max)
+		}
+		if len(b) > 0 && err == io.EOF {
+			err = nil
+		}
+		if err != nil {
+			return "", err
+		}
+		line := strings.TrimRight(string(b), "\r\n")
+		if strings.TrimSpace(line) != "" {
+			return line, nil
+		}
+		p.start = p.off
+	}
+}
+
+// binaryHeader reads the "size dim" header of binary model data.
+func (p *parser) binaryHeader() (size, dim int, err error) {
+	line, err := p.line(64)
+	if err != nil {
+		return 0, 0, p.error(err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return 0, 0, p.errorf("could not extract size/dim from binary model data")
+	}
+	size, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, p.errorf("invalid size: %v", err)
+	}
+	dim, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, p.errorf("invalid dimension: %v", err)
+	}
+	return size, dim, p.checkHeader(size, dim)
+}
+
+// binaryWord reads a space-terminated word from binary model data, skipping a leading
+// newline left by the previous entry.
+func (p *parser) binaryWord() (string, error) {
+	max := p.o.limits.WordLength
+	buf := p.buf[:0]
+	for {
+		c, err := p.br.ReadByte()
+		if err != nil {
+			return "", p.error(err)
+		}
+		p.off++
+		if c == ' ' {
+			break
+		}
+		if c == '\n' && len(buf) == 0 {
+			p.start = p.off
+			continue
+		}
+		if len(buf) == max {
+			return "", p.errorf("word too long (limit %d bytes)", max)
+		}
+		buf = append(buf, c)
+	}
+	p.buf = buf
+	if len(buf) == 0 {
+		return "", p.error(errEmptyWord)
+	}
+	return string(buf), nil
+}
+
+// decodeVector decodes little-endian float32 values from b into v.
+func decodeVector(b []byte, v Vector) error {
+	for i := range v {
+		x := math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return errNonFinite
+		}
+		v[i] = x
+	}
+	return nil
+}
+
+// parseTextEntry parses a line of the form "word x1 x2 ... xdim" into w and v.  The
+// vector components are taken from the end of the line so that words containing spaces
+// are preserved.  Words longer than maxWord bytes are an error.
+func parseTextEntry(line string, v Vector, maxWord int) (string, error) {
+	dim := len(v)
+	fields := strings.Fields(line)
+	if len(fields) < dim+1 {
+		return "", fmt.Errorf("expected word and %d values, got %d fields", dim, len(fields))
+	}
+
+	n := len(fields) - dim
+	for j, f := range fields[n:] {
+		x, err := strconv.ParseFloat(f, 32)
+		if err != nil {
+			return "", fmt.Errorf("error parsing value %d: %v", j, err)
+		}
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return "", errNonFinite
+		}
+		v[j] = float32(x)
+	}
+
+	w := strings.Join(fields[:n], " ")
+	if len(w) > maxWord {
+		return "", fmt.Errorf("word too long (limit %d bytes)", maxWord)
+	}
+	return w, nil
+}
@@ -0,0 +1,251 @@
+package word2vec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	valid := testModelData(t, []string{"hello", "world"}, []Vector{{0, 1}, {1, 0}})
+	// Offset of the second entry: "2 2\n" + "hello " + 8 bytes + "\n".
+	second := int64(4 + 6 + 8 + 1)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		binary bool // use FromReader rather than Load
+		opts   []Option
+		entry  int
+		offset int64
+		err    error
+	}{
+		{
+			name:  "empty",
+			data:  nil,
+			entry: -1,
+			err:   io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "bad header",
+			data:   []byte("2 x\n"),
+			binary: true,
+			entry:  -1,
+		},
+		{
+			name:  "size limit",
+			data:  []byte("1000000000 300\n"),
+			entry: -1,
+		},
+		{
+			name:  "dim limit",
+			data:  valid,
+			opts:  []Option{WithLimits(Limits{Size: 10, Dim: 1, WordLength: 10})},
+			entry: -1,
+		},
+		{
+			name:   "truncated vector",
+			data:   valid[:len(valid)-3],
+			entry:  1,
+			offset: second,
+			err:    io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "truncated entries",
+			data:   append([]byte("3 2\n"), valid[4:]...),
+			entry:  2,
+			offset: int64(len(valid)),
+			err:    io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "word length",
+			data:   valid,
+			opts:   []Option{WithLimits(Limits{Size: 10, Dim: 10, WordLength: 4})},
+			entry:  0,
+			offset: 4,
+		},
+		{
+			name:   "duplicate",
+			data:   testModelData(t, []string{"hello", "hello"}, []Vector{{0, 1}, {1, 0}}),
+			entry:  1,
+			offset: second,
+			err:    errDuplicateWord,
+		},
+		{
+			name:   "non-finite",
+			data:   testModelData(t, []string{"hello", "world"}, []Vector{{0, 1}, {float32(math.Inf(1)), 0}}),
+			entry:  1,
+			offset: second,
+			err:    errNonFinite,
+		},
+		{
+			name:   "text truncated",
+			data:   []byte("3 2\nhello 0 1\nworld 1 0\n"),
+			entry:  2,
+			offset: 24,
+			err:    io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "text bad value",
+			data:   []byte("2 2\nhello 0 1\nworld 1 x\n"),
+			entry:  1,
+			offset: 14,
+		},
+		{
+			name:   "text duplicate",
+			data:   []byte("hello 0 1\n\nhello 1 0\n"),
+			entry:  1,
+			offset: 11,
+			err:    errDuplicateWord,
+		},
+	}
+
+	for _, tt := range tests {
+		load := Load
+		if tt.binary {
+			load = FromReader
+		}
+		_, err := load(bytes.NewReader(tt.data), tt.opts...)
+		if err == nil {
+			t.Errorf("[%s] expected error from Load", tt.name)
+			continue
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("[%s] Load() error = %v (%T), expected *ParseError", tt.name, err, err)
+			continue
+		}
+		if perr.Entry != tt.entry || perr.Offset != tt.offset {
+			t.Errorf("[%s] Load() error at entry %d offset %d, expected entry %d offset %d: %v",
+				tt.name, perr.Entry, perr.Offset, tt.entry, tt.offset, err)
+		}
+		if tt.err != nil && !errors.Is(perr.Err, tt.err) {
+			t.Errorf("[%s] Load() error = %v, expected %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestDuplicateWordError(t *testing.T) {
+	words := []string{"hello", "hello"}
+	vecs := []Vector{{0, 1}, {1, 0}}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "binary", data: testModelData(t, words, vecs)},
+		{name: "text", data: []byte("2 2\nhello 0 1\nhello 1 0\n")},
+		{name: "fastText", data: testFastTextData(t, words, vecs, 3, 6, nil)},
+	}
+
+	// The streaming (binary, text) and serial (fastText) loaders wrap the builder error
+	// in the same way.
+	expected := fmt.Errorf("%w: %q", errDuplicateWord, "hello").Error()
+	for _, tt := range tests {
+		_, err := Load(bytes.NewReader(tt.data))
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("[%s] Load() error = %v (%T), expected *ParseError", tt.name, err, err)
+			continue
+		}
+		if !errors.Is(perr.Err, errDuplicateWord) || perr.Err.Error() != expected {
+			t.Errorf("[%s] Load() error = %v, expected %v wrapping errDuplicateWord", tt.name, perr.Err, expected)
+		}
+	}
+}
+
+func TestAllowDuplicates(t *testing.T) {
+	data := testModelData(t, []string{"hello", "hello", "world"}, []Vector{{0, 1}, {0, 3}, {1, 0}})
+
+	m, err := FromReader(bytes.NewReader(data), AllowDuplicates(), Merge(MergeAverage), RawVectors())
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	if m.Size() != 2 {
+		t.Errorf("m.Size() = %d, expected 2", m.Size())
+	}
+	if v := m.Map([]string{"hello"})["hello"]; !approxEqual(v[1], 2) {
+		t.Errorf("vector for hello = %v, expected [0 2]", v)
+	}
+}
+
+func fuzzSeeds(f *testing.F) {
+	bin := testModelData(f, []string{"hello", "world"}, []Vector{{0, 1}, {1, 0}})
+	f.Add(bin)
+	f.Add([]byte("2 2\nhello 0 1\nworld 1 0\n"))
+	f.Add([]byte("hello 0 1\nworld 1 0\n"))
+	f.Add([]byte("1 3\nhello 0 1 2e10\n"))
+	f.Add(testFastTextData(f, []string{"hello"}, []Vector{{0, 1}}, 3, 4, []Vector{{1, 0}, {0, 1}}))
+
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	zw.Write(bin)
+	zw.Close()
+	f.Add(buf.Bytes())
+
+	m, err := FromReader(bytes.NewReader(bin))
+	if err != nil {
+		f.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	buf = &bytes.Buffer{}
+	if err := m.WriteMmap(buf); err != nil {
+		f.Fatalf("unexpected error from WriteMmap: %v", err)
+	}
+	f.Add(buf.Bytes())
+
+	buf = &bytes.Buffer{}
+	if err := m.Convert(Int8Storage).WriteMmap(buf); err != nil {
+		f.Fatalf("unexpected error from WriteMmap: %v", err)
+	}
+	f.Add(buf.Bytes())
+
+	buf = &bytes.Buffer{}
+	if err := m.ConvertPQ(PQConfig{Subspaces: 1}).WriteMmap(buf); err != nil {
+		f.Fatalf("unexpected error from WriteMmap: %v", err)
+	}
+	f.Add(buf.Bytes())
+}
+
+func FuzzLoad(f *testing.F) {
+	fuzzSeeds(f)
+	limits := Limits{Size: 1 << 10, Dim: 1 << 8, WordLength: 1 << 8}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := Load(bytes.NewReader(data), WithLimits(limits))
+		if err != nil {
+			return
+		}
+		if m.Dim() <= 0 {
+			t.Errorf("m.Dim() = %d, expected > 0", m.Dim())
+		}
+		m.Range(func(id int, w string, v Vector) bool {
+			if len(v) != m.Dim() {
+				t.Errorf("len(v) = %d, expected %d", len(v), m.Dim())
+			}
+			if _, ok := m.vocab.(*mapVocab); !ok {
+				return true
+			}
+			if i, err := m.ID(w); err != nil || i != id {
+				t.Errorf("m.ID(%q) = %d, %v, expected %d", w, i, err, id)
+			}
+			return true
+		})
+	})
+}
+
+func FuzzFromMmap(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := readMmap(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		m.Range(func(id int, w string, v Vector) bool {
+			m.ID(w)
+			m.CosN(Expr{w: 1}, 1)
+			return true
+		})
+	})
+}
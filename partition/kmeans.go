@@ -0,0 +1,290 @@
+package partition
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"code.sajari.com/word2vec"
+)
+
+// KMeansConfig is a type which configures the clustering of the words of a model into
+// classes (see KMeans).  Zero fields take their default values, except Classes which must
+// be set.
+type KMeansConfig struct {
+	// Classes is the number of classes, between 1 and the number of words in the model.
+	Classes int
+
+	// Iterations is the maximum number of k-means iterations.  Clustering stops early
+	// if an iteration does not change the class of any word.  Defaults to 10, as in the
+	// original word2vec tool.
+	Iterations int
+
+	// Workers is the number of goroutines used to assign words to classes.  Defaults to
+	// GOMAXPROCS.
+	Workers int
+
+	// Seed seeds the random choice of initial centroids.
+	Seed int64
+}
+
+func (c KMeansConfig) withDefaults() KMeansConfig {
+	if c.Iterations <= 0 {
+		c.Iterations = 10
+	}
+	if c.Workers <= 0 {
+		c.Workers = runtime.GOMAXPROCS(0)
+	}
+	return c
+}
+
+// KMeans clusters the words of m into classes using spherical k-means (words are
+// assigned to the centroid with the greatest cosine similarity to their vector, and
+// centroids are normalised), with initial centroids chosen by k-means++.  Word classes
+// are numbered from 0 to Classes-1, and none are empty.  The words of the Partition are
+// in ID order (see Partition.Write).  Vectors which m stores with reduced precision (see
+// word2vec.Storage) are decoded into a float32 copy for clustering.  Returns ctx.Err() if
+// ctx is done before clustering finishes.
+func KMeans(ctx context.Context, m *word2vec.Model, c KMeansConfig) (*Partition, error) {
+	c = c.withDefaults()
+	if c.Classes <= 0 || c.Classes > m.Size() {
+		return nil, fmt.Errorf("invalid number of classes %d: must be between 1 and the model size %d", c.Classes, m.Size())
+	}
+
+	km := newKMeans(m, c)
+	if err := km.init(ctx, rand.New(rand.NewSource(c.Seed))); err != nil {
+		return nil, err
+	}
+	for it := 0; it < c.Iterations; it++ {
+		changed, err := km.assign(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if changed == 0 && it > 0 {
+			break
+		}
+		km.update()
+	}
+
+	p := &Partition{
+		words:   make(map[string]int, m.Size()),
+		classes: make(map[int][]string, c.Classes),
+	}
+	m.Range(func(i int, w string, _ word2vec.Vector) bool {
+		p.add(w, int(km.class[i]))
+		return true
+	})
+	return p, nil
+}
+
+// kmeans holds the state of spherical k-means clustering of the vectors of a model.
+type kmeans struct {
+	m       *word2vec.Model
+	c       KMeansConfig
+	dim     int
+	vecs    []float32 // float32 copy of the vectors, nil if the model stores float32
+	scales  []float32 // factors which normalise the vector of each word
+	cent    []float32 // normalised centroid of each class
+	class   []int32   // class of each word
+	sim     []float32 // cosine similarity of each word to the centroid of its class
+	partial []partialSums
+}
+
+// partialSums holds the sums of the vectors in each class, for a range of words.
+type partialSums struct {
+	sums   []float32
+	counts []int
+}
+
+func newKMeans(m *word2vec.Model, c KMeansConfig) *kmeans {
+	km := &kmeans{
+		m:       m,
+		c:       c,
+		dim:     m.Dim(),
+		scales:  make([]float32, m.Size()),
+		cent:    make([]float32, c.Classes*m.Dim()),
+		class:   make([]int32, m.Size()),
+		sim:     make([]float32, m.Size()),
+		partial: make([]partialSums, c.Workers),
+	}
+	for i := range km.partial {
+		km.partial[i] = partialSums{
+			sums:   make([]float32, c.Classes*km.dim),
+			counts: make([]int, c.Classes),
+		}
+	}
+	// Vectors stored with reduced precision are decoded on each access, so decode them
+	// once rather than in every iteration.
+	if m.Storage() != word2vec.Float32Storage {
+		km.vecs = make([]float32, m.Size()*km.dim)
+	}
+	m.Range(func(i int, _ string, v word2vec.Vector) bool {
+		if km.vecs != nil {
+			copy(km.vecs[i*km.dim:], v)
+		}
+		if n := v.Norm(); n != 0 {
+			km.scales[i] = 1 / n
+		}
+		return true
+	})
+	return km
+}
+
+// centroid returns the centroid of class j.
+func (km *kmeans) centroid(j int) word2vec.Vector {
+	return word2vec.Vector(km.cent[j*km.dim : (j+1)*km.dim])
+}
+
+// vector returns the vector of the word with ID i (not normalised).
+func (km *kmeans) vector(i int) word2vec.Vector {
+	if km.vecs != nil {
+		return word2vec.Vector(km.vecs[i*km.dim : (i+1)*km.dim])
+	}
+	v, _ := km.m.VectorByID(i)
+	return v
+}
+
+// each calls f for each of the Workers ranges of word IDs in parallel, with the index of
+// the range.  Returns ctx.Err() if ctx is done.
+func (km *kmeans) each(ctx context.Context, f func(w, lo, hi int)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	n := km.m.Size()
+	var wg sync.WaitGroup
+	wg.Add(km.c.Workers)
+	for w := 0; w < km.c.Workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			f(w, w*n/km.c.Workers, (w+1)*n/km.c.Workers)
+		}(w)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// init chooses the initial centroids using k-means++: the first is a random word, and
+// each following centroid is a word chosen with probability proportional to its cosine
+// distance to the nearest centroid so far.
+func (km *kmeans) init(ctx context.Context, r *rand.Rand) error {
+	dist := make([]float64, km.m.Size())
+	i := r.Intn(km.m.Size())
+	for j := 0; j < km.c.Classes; j++ {
+		km.centroid(j).Add(km.scales[i], km.vector(i))
+
+		err := km.each(ctx, func(_, lo, hi int) {
+			for k := lo; k < hi; k++ {
+				d := 1 - float64(km.centroid(j).Dot(km.vector(k))*km.scales[k])
+				if j == 0 || d < dist[k] {
+					dist[k] = d
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		var total float64
+		for _, d := range dist {
+			total += math.Max(d, 0)
+		}
+		if total == 0 {
+			// The remaining words are equal to centroids: choose any.
+			i = r.Intn(km.m.Size())
+			continue
+		}
+		u := r.Float64() * total
+		for i = 0; i < len(dist)-1; i++ {
+			if u -= math.Max(dist[i], 0); u < 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// assign assigns each word to the class whose centroid is most similar to its vector,
+// and sums the vectors of each class.  Returns the number of words whose class changed.
+func (km *kmeans) assign(ctx context.Context) (int, error) {
+	changed := make([]int, km.c.Workers)
+	err := km.each(ctx, func(w, lo, hi int) {
+		p := km.partial[w]
+		for i := range p.sums {
+			p.sums[i] = 0
+		}
+		for i := range p.counts {
+			p.counts[i] = 0
+		}
+
+		for i := lo; i < hi; i++ {
+			v := km.vector(i)
+			best, sim := int32(0), float32(math.Inf(-1))
+			for j := 0; j < km.c.Classes; j++ {
+				if s := km.centroid(j).Dot(v); s > sim {
+					best, sim = int32(j), s
+				}
+			}
+			if best != km.class[i] {
+				changed[w]++
+			}
+			km.class[i] = best
+			km.sim[i] = sim * km.scales[i]
+			word2vec.Vector(p.sums[int(best)*km.dim:(int(best)+1)*km.dim]).Add(km.scales[i], v)
+			p.counts[best]++
+		}
+	})
+
+	var n int
+	for _, x := range changed {
+		n += x
+	}
+	return n, err
+}
+
+// update sets each centroid to the normalised mean of the vectors in its class.  Empty
+// classes take the word which is least similar to the centroid of its class (from a
+// class with more than one word).
+func (km *kmeans) update() {
+	for i := range km.cent {
+		km.cent[i] = 0
+	}
+	counts := make([]int, km.c.Classes)
+	for _, p := range km.partial {
+		word2vec.Vector(km.cent).Add(1, p.sums)
+		for j, n := range p.counts {
+			counts[j] += n
+		}
+	}
+
+	for j, n := range counts {
+		if n > 0 {
+			continue
+		}
+		worst := -1
+		for i, c := range km.class {
+			if counts[c] > 1 && (worst < 0 || km.sim[i] < km.sim[worst]) {
+				worst = i
+			}
+		}
+		if worst < 0 {
+			break
+		}
+		c := int(km.class[worst])
+		v := km.vector(worst)
+		km.centroid(c).Add(-km.scales[worst], v)
+		km.centroid(j).Add(km.scales[worst], v)
+		counts[c]--
+		counts[j]++
+		km.class[worst] = int32(j)
+		km.sim[worst] = 1
+	}
+
+	for j := range counts {
+		if c := km.centroid(j); c.Norm() != 0 {
+			c.Normalise()
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package partition
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.sajari.com/word2vec"
+)
+
+// testClusteredModel returns a model of n words in each of the given number of clusters,
+// named by cluster (a0, a1, ..., b0, ...), whose vectors are close to the basis vector of
+// their cluster.
+func testClusteredModel(t *testing.T, clusters, n int) *word2vec.Model {
+	r := rand.New(rand.NewSource(1))
+	dim := clusters + 2
+	b := &strings.Builder{}
+	fmt.Fprintln(b, clusters*n, dim)
+	for i := 0; i < n; i++ {
+		for c := 0; c < clusters; c++ {
+			fmt.Fprintf(b, "%c%d", 'a'+c, i)
+			for j := 0; j < dim; j++ {
+				x := 0.2 * r.NormFloat64()
+				if j == c {
+					x += 1
+				}
+				fmt.Fprintf(b, " %v", x)
+			}
+			b.WriteByte('\n')
+		}
+	}
+	m, err := word2vec.FromTextReader(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("unexpected error from FromTextReader: %v", err)
+	}
+	return m
+}
+
+func TestKMeans(t *testing.T) {
+	m := testClusteredModel(t, 3, 30)
+	tests := []struct {
+		workers int
+		storage word2vec.Storage
+	}{
+		{1, word2vec.Float32Storage},
+		{4, word2vec.Float32Storage},
+		{4, word2vec.Int8Storage},
+	}
+	for _, tt := range tests {
+		p, err := KMeans(context.Background(), m.Convert(tt.storage), KMeansConfig{Classes: 3, Workers: tt.workers})
+		if err != nil {
+			t.Fatalf("[workers %d, %v] unexpected error from KMeans: %v", tt.workers, tt.storage, err)
+		}
+		if p.Classes() != 3 || p.Size() != 90 {
+			t.Errorf("[workers %d, %v] %d classes, %d words, expected 3 classes, 90 words", tt.workers, tt.storage, p.Classes(), p.Size())
+		}
+
+		seen := make(map[int]bool)
+		for _, c := range "abc" {
+			class, err := p.Class(fmt.Sprintf("%c0", c))
+			if err != nil {
+				t.Fatalf("[workers %d, %v] unexpected error from Class: %v", tt.workers, tt.storage, err)
+			}
+			if seen[class] {
+				t.Errorf("[workers %d, %v] cluster %c has class %d, expected a different class to the other clusters", tt.workers, tt.storage, c, class)
+			}
+			seen[class] = true
+
+			for i := 1; i < 30; i++ {
+				w := fmt.Sprintf("%c%d", c, i)
+				if ok, err := p.Equiv(fmt.Sprintf("%c0", c), w); err != nil || !ok {
+					t.Errorf("[workers %d, %v] Equiv(%c0, %s) = %v, %v, expected true, nil", tt.workers, tt.storage, c, w, ok, err)
+				}
+			}
+		}
+	}
+}
+
+func TestKMeansClasses(t *testing.T) {
+	m := testClusteredModel(t, 3, 5)
+	tests := []struct {
+		classes int
+		err     bool
+	}{
+		{0, true},
+		{1, false},
+		{7, false},
+		{15, false},
+		{16, true},
+	}
+
+	for _, tt := range tests {
+		p, err := KMeans(context.Background(), m, KMeansConfig{Classes: tt.classes, Seed: 2})
+		if (err != nil) != tt.err {
+			t.Errorf("[%d] KMeans() error = %v, expected error: %v", tt.classes, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		// No class is empty.
+		if p.Classes() != tt.classes {
+			t.Errorf("[%d] Classes() = %d, expected %d", tt.classes, p.Classes(), tt.classes)
+		}
+		for i := 0; i < tt.classes; i++ {
+			if c, err := p.EquivClassIndex(i); err != nil || len(c) == 0 {
+				t.Errorf("[%d] EquivClassIndex(%d) = %v, %v, expected non-empty class", tt.classes, i, c, err)
+			}
+		}
+	}
+}
+
+func TestKMeansCancel(t *testing.T) {
+	m := testClusteredModel(t, 3, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := KMeans(ctx, m, KMeansConfig{Classes: 3}); err != context.Canceled {
+		t.Errorf("KMeans() error = %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestPartitionWrite(t *testing.T) {
+	data := "hello 1\nworld 0\nfoo 1\n"
+	p, err := NewPartition(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from NewPartition: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := p.Write(buf); err != nil {
+		t.Fatalf("unexpected error from Write: %v", err)
+	}
+	if buf.String() != data {
+		t.Errorf("Write() wrote %q, expected %q", buf.String(), data)
+	}
+
+	m := testClusteredModel(t, 3, 10)
+	p, err = KMeans(context.Background(), m, KMeansConfig{Classes: 3})
+	if err != nil {
+		t.Fatalf("unexpected error from KMeans: %v", err)
+	}
+	buf.Reset()
+	if err := p.Write(buf); err != nil {
+		t.Fatalf("unexpected error from Write: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "a0 ") {
+		t.Errorf("Write() wrote %q..., expected words in ID order", buf.String()[:10])
+	}
+	q, err := NewPartition(buf)
+	if err != nil {
+		t.Fatalf("unexpected error from NewPartition: %v", err)
+	}
+	for _, w := range []string{"a0", "b3", "c9"} {
+		c, _ := p.Class(w)
+		d, err := q.Class(w)
+		if err != nil || c != d {
+			t.Errorf("Class(%q) = %d, %v after Write and NewPartition, expected %d, nil", w, d, err, c)
+		}
+	}
+	if !reflect.DeepEqual(p.classes, q.classes) {
+		t.Errorf("classes after Write and NewPartition = %v, expected %v", q.classes, p.classes)
+	}
+}
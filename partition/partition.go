@@ -24,6 +24,7 @@ func (e NotFoundError) Error() string {
 type Partition struct {
 	words   map[string]int
 	classes map[int][]string
+	order   []string // words in the order they were added
 	size    int
 }
 
@@ -31,8 +32,10 @@ type Partition struct {
 // to be of the output format from the word2vec command with -classes arg).
 func NewPartition(r io.Reader) (*Partition, error) {
 	scanner := bufio.NewScanner(r)
-	words := make(map[string]int)
-	classes := make(map[int][]string)
+	p := &Partition{
+		words:   make(map[string]int),
+		classes: make(map[int][]string),
+	}
 
 	i := 0
 	for scanner.Scan() {
@@ -49,8 +52,7 @@ func NewPartition(r io.Reader) (*Partition, error) {
 			return nil, fmt.Errorf("[line: %d] error parsing integer %#v: %v", i+1, fields[1], err)
 		}
 
-		words[w] = c
-		classes[c] = append(classes[c], w)
+		p.add(w, c)
 		i++
 	}
 
@@ -58,11 +60,30 @@ func NewPartition(r io.Reader) (*Partition, error) {
 	if err != nil {
 		return nil, fmt.Errorf("[line: %d] scanner error: %v", i+1, err)
 	}
+	return p, nil
+}
 
-	return &Partition{
-		words:   words,
-		classes: classes,
-	}, nil
+// add adds the word w to class c.
+func (p *Partition) add(w string, c int) {
+	if _, ok := p.words[w]; !ok {
+		p.order = append(p.order, w)
+	}
+	p.words[w] = c
+	p.classes[c] = append(p.classes[c], w)
+}
+
+// Write writes the partition to w in the format read by NewPartition (the output format
+// of the word2vec command with -classes arg): a line with each word and its class,
+// separated by a space.  Words are written in the order they were read (or in ID order
+// for partitions created by KMeans).
+func (p *Partition) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, word := range p.order {
+		if _, err := fmt.Fprintf(bw, "%s %d\n", word, p.words[word]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
 }
 
 // Classes returns the number of classes in the partition.
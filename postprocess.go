@@ -0,0 +1,191 @@
+package word2vec
+
+import (
+	"fmt"
+	"math"
+)
+
+// AllButTheTop is an Option which post-processes the vectors of the model after loading,
+// subtracting their mean and removing their top d principal components (see
+// Model.AllButTheTop).  With d = 0 the vectors are only mean-centred.  Loading returns an
+// error if d is not less than the dimension of the model, or if Subwords is also given.
+func AllButTheTop(d int) Option {
+	return func(o *options) {
+		o.postProcess = true
+		o.abtt = d
+	}
+}
+
+// AllButTheTop returns a Model with the vocabulary of m, whose vectors are those of m
+// with their mean subtracted and their projections onto their top d principal components
+// removed ("all-but-the-top", Mu and Viswanath, 2018), and then re-normalised (unless m
+// was loaded with RawVectors).  Word vectors are dominated by a few directions common to
+// all words, which mostly encode frequency; removing them makes the vectors more
+// isotropic (see MeasureIsotropy), which usually improves similarity rankings.  A d of
+// about Dim()/100 is typical, and d = 0 only mean-centres the vectors.
+//
+// The vectors are stored as float32 and subword vectors are not kept.  Returns an error
+// if d is negative or not less than the dimension of m.
+func (m *Model) AllButTheTop(d int) (*Model, error) {
+	if d < 0 || d >= m.dim {
+		return nil, fmt.Errorf("invalid number of components %d: must be between 0 and %d", d, m.dim-1)
+	}
+
+	mean := make(Vector, m.dim)
+	top := make([]Vector, d)
+	if d == 0 {
+		mv, _ := meanVariance(m)
+		for i, x := range mv {
+			mean[i] = float32(x)
+		}
+	} else {
+		pca, err := PCA(m, PCAConfig{Dim: d})
+		if err != nil {
+			return nil, err
+		}
+		copy(mean, pca.mean)
+		for k := range top {
+			top[k] = make(Vector, m.dim)
+			for i := range top[k] {
+				top[k][i] = pca.w[i*d+k]
+			}
+		}
+	}
+
+	// The components are orthonormal, so the projection of v - mean onto each of them
+	// can be removed in turn.
+	size := m.vocab.size()
+	st := &float32Storage{dim: m.dim, vecs: make([]float32, size*m.dim)}
+	norms := make([]float32, size)
+	parallel(size, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			v := st.row(i)
+			copy(v, m.row(i))
+			v.Add(-1, mean)
+			for _, u := range top {
+				v.Add(-v.Dot(u), u)
+			}
+			norms[i] = v.Norm()
+			if !m.raw && norms[i] != 0 {
+				v.Normalise()
+			}
+		}
+	})
+
+	c := *m
+	c.sub = nil
+	c.norms = norms
+	return c.convert(st), nil
+}
+
+// Isotropy is a type which describes how uniformly the vectors of a model are spread in
+// direction (see MeasureIsotropy).  Vectors are normalised before they are measured.
+type Isotropy struct {
+	Words int // number of words measured
+
+	// MeanCosine is the mean cosine similarity of pairs of distinct words, which is
+	// close to 0 for isotropic vectors.
+	MeanCosine float64
+
+	// MeanNorm is the norm of the mean of the normalised vectors, which is close to 0
+	// for isotropic vectors.
+	MeanNorm float64
+
+	// TopVariance is the fraction of the variance of the vectors along their first
+	// principal component, which is close to 1/Dim() for isotropic vectors.
+	TopVariance float64
+
+	// Partition is the isotropy measure of Mu and Viswanath: the ratio of the minimum to
+	// the maximum of the partition function Z(c) = sum exp(c . v) over the principal
+	// directions ±c of the vectors v.  It is close to 1 for isotropic vectors, and close
+	// to 0 for vectors which are dominated by a few directions.
+	Partition float64
+}
+
+func (s Isotropy) String() string {
+	return fmt.Sprintf("%d words: mean cosine %.4f, mean norm %.4f, top component variance %.4f, partition isotropy %.4f",
+		s.Words, s.MeanCosine, s.MeanNorm, s.TopVariance, s.Partition)
+}
+
+// MeasureIsotropy measures the isotropy of the vectors of up to k words spread evenly
+// through the vocabulary of m (all words if k <= 0).  The cost is proportional to
+// k * Dim()^2.
+func MeasureIsotropy(m *Model, k int) Isotropy {
+	size := m.vocab.size()
+	if k <= 0 || k > size {
+		k = size
+	}
+	dim := m.dim
+	vecs := make([]float64, k*dim)
+	for i := 0; i < k; i++ {
+		v := m.row(i * size / k)
+		n := v.Norm()
+		if n == 0 {
+			continue
+		}
+		for j, x := range v {
+			vecs[i*dim+j] = float64(x / n)
+		}
+	}
+
+	s := Isotropy{Words: k}
+	if k < 2 {
+		return s
+	}
+
+	// Sums of the vectors and of their outer products.
+	sum := make([]float64, dim)
+	gram := make([]float64, dim*dim)
+	var sq float64
+	for i := 0; i < k; i++ {
+		v := vecs[i*dim : (i+1)*dim]
+		for x, vx := range v {
+			sum[x] += vx
+			sq += vx * vx
+			for y, vy := range v {
+				gram[x*dim+y] += vx * vy
+			}
+		}
+	}
+	var sumSq float64
+	for _, x := range sum {
+		sumSq += x * x
+	}
+	n := float64(k)
+	s.MeanNorm = math.Sqrt(sumSq) / n
+	s.MeanCosine = (sumSq - sq) / (n * (n - 1))
+
+	// The covariance is gram / n - mean mean^T.
+	cov := make([]float64, dim*dim)
+	for x := 0; x < dim; x++ {
+		for y := 0; y < dim; y++ {
+			cov[x*dim+y] = gram[x*dim+y]/n - sum[x]*sum[y]/(n*n)
+		}
+	}
+	if ev, _ := svd(cov, dim, dim); ev[0] > 0 {
+		var total float64
+		for _, x := range ev {
+			total += x
+		}
+		s.TopVariance = ev[0] / total
+	}
+
+	// The principal directions are the eigenvectors of gram (the columns of u), whose
+	// signs are arbitrary, so both c and -c are used.
+	svd(gram, dim, dim)
+	min, max := math.Inf(1), 0.0
+	for c := 0; c < dim; c++ {
+		var zp, zn float64
+		for i := 0; i < k; i++ {
+			var dot float64
+			for j, x := range vecs[i*dim : (i+1)*dim] {
+				dot += x * gram[j*dim+c]
+			}
+			zp += math.Exp(dot)
+			zn += math.Exp(-dot)
+		}
+		min, max = math.Min(min, math.Min(zp, zn)), math.Max(max, math.Max(zp, zn))
+	}
+	s.Partition = min / max
+	return s
+}
@@ -0,0 +1,132 @@
+package word2vec
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// testAnisotropicModelData returns model data of size random words of dimension dim,
+// with a large vector common to all words, and most variance along one direction.
+func testAnisotropicModelData(t *testing.T, size, dim int) []byte {
+	r := rand.New(rand.NewSource(2))
+	common := make(Vector, dim)
+	top := make(Vector, dim)
+	for j := range common {
+		common[j] = float32(r.NormFloat64())
+		top[j] = float32(r.NormFloat64())
+	}
+	return testRandomModelData(t, size, dim, 1, func(r *rand.Rand, v Vector) {
+		for j := range v {
+			v[j] *= 0.3
+		}
+		v.Add(1, common)
+		v.Add(float32(r.NormFloat64()), top)
+	})
+}
+
+func TestAllButTheTop(t *testing.T) {
+	data := testAnisotropicModelData(t, 1000, 16)
+	m, err := FromReader(bytes.NewReader(data), RawVectors())
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	before := MeasureIsotropy(m, 0)
+	if before.Words != 1000 || before.MeanCosine < 0.3 || before.Partition > 0.5 {
+		t.Errorf("MeasureIsotropy() = %v, expected 1000 words, mean cosine > 0.3, partition < 0.5", before)
+	}
+
+	pca, err := PCA(m, PCAConfig{Dim: 1})
+	if err != nil {
+		t.Fatalf("unexpected error from PCA: %v", err)
+	}
+	top := Vector(pca.w)
+
+	for _, d := range []int{0, 1} {
+		pp, err := m.AllButTheTop(d)
+		if err != nil {
+			t.Fatalf("[%d] unexpected error from AllButTheTop: %v", d, err)
+		}
+		if pp.Dim() != 16 || pp.Size() != 1000 {
+			t.Errorf("[%d] processed model dim %d, size %d, expected dim 16, size 1000", d, pp.Dim(), pp.Size())
+		}
+
+		// The mean of the (raw) vectors is removed, and with d = 1 so is the top component.
+		mean := make(Vector, 16)
+		var dot float32
+		pp.Range(func(_ int, _ string, v Vector) bool {
+			mean.Add(1.0/1000, v)
+			dot += v.Dot(top) * v.Dot(top) / 1000
+			return true
+		})
+		if n := mean.Norm(); n > 1e-4 {
+			t.Errorf("[%d] norm of mean vector = %v, expected 0", d, n)
+		}
+		if d == 1 && dot > 1e-6 {
+			t.Errorf("[%d] mean squared projection onto top component = %v, expected 0", d, dot)
+		}
+
+		after := MeasureIsotropy(pp, 0)
+		if after.MeanCosine > 0.01 || after.MeanNorm > before.MeanNorm/10 {
+			t.Errorf("[%d] MeasureIsotropy() = %v after, expected mean cosine < 0.01, mean norm < %v", d, after, before.MeanNorm/10)
+		}
+		if d == 1 && (after.Partition < 0.8 || after.TopVariance > before.TopVariance/2) {
+			t.Errorf("[%d] MeasureIsotropy() = %v after, %v before, expected more isotropic", d, after, before)
+		}
+	}
+
+	for _, d := range []int{-1, 16} {
+		if _, err := m.AllButTheTop(d); err == nil {
+			t.Errorf("AllButTheTop(%d) returned nil error", d)
+		}
+	}
+}
+
+func TestMeasureIsotropy(t *testing.T) {
+	m, err := FromReader(bytes.NewReader(testRandomModelData(t, 1000, 16, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	s := MeasureIsotropy(m, 500)
+	if s.Words != 500 || s.MeanCosine > 0.01 || s.MeanNorm > 0.1 || s.TopVariance > 0.15 || s.Partition < 0.9 {
+		t.Errorf("MeasureIsotropy() = %v for random vectors, expected 500 words, isotropic", s)
+	}
+}
+
+func TestAllButTheTopOption(t *testing.T) {
+	data := testAnisotropicModelData(t, 200, 8)
+	m, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	expected, err := m.AllButTheTop(2)
+	if err != nil {
+		t.Fatalf("unexpected error from AllButTheTop: %v", err)
+	}
+
+	pp, err := FromReader(bytes.NewReader(data), AllButTheTop(2))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	if pp.Fingerprint() != expected.Fingerprint() {
+		t.Errorf("model loaded with AllButTheTop has fingerprint %v, expected %v", pp.Fingerprint(), expected.Fingerprint())
+	}
+	v := pp.Map([]string{"aaa"})["aaa"]
+	if n := v.Norm(); !approxEqual(n, 1) {
+		t.Errorf("norm of processed vector = %v, expected 1", n)
+	}
+
+	if _, err := FromReader(bytes.NewReader(data), AllButTheTop(8)); err == nil {
+		t.Errorf("FromReader() with AllButTheTop(8) returned nil error")
+	}
+
+	// Subword vectors are not post-processed, so they can't be used with AllButTheTop.
+	ft := testFastTextData(t, []string{"hello", "world"}, []Vector{{1, 0}, {0, 1}}, 2, 3, nil)
+	if _, err := FromFastText(bytes.NewReader(ft), AllButTheTop(0)); err != nil {
+		t.Errorf("unexpected error from FromFastText with AllButTheTop(0): %v", err)
+	}
+	if _, err := FromFastText(bytes.NewReader(ft), Subwords(), AllButTheTop(0)); err == nil {
+		t.Errorf("FromFastText() with Subwords and AllButTheTop returned nil error")
+	}
+}
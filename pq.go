@@ -0,0 +1,234 @@
+package word2vec
+
+import (
+	"math"
+	"math/rand"
+)
+
+// PQConfig is a type which configures product quantisation (see PQStorage).  Zero fields
+// take their default values.
+type PQConfig struct {
+	// Subspaces is the number of sub-spaces the vectors are split into, which is the
+	// number of bytes used to store each vector.  Defaults to a quarter of the dimension
+	// (rounded up).
+	Subspaces int
+
+	// Centroids is the number of centroids in the codebook of each sub-space, at most
+	// 256.  Defaults to 256.
+	Centroids int
+
+	// Iterations is the number of k-means iterations used to train the codebooks.
+	// Defaults to 20.
+	Iterations int
+
+	// Sample is the number of vectors used to train the codebooks.  Defaults to 65536.
+	Sample int
+
+	// Seed seeds the random choice of initial centroids.
+	Seed int64
+}
+
+// WithPQ is an Option which stores the vectors of the model using product quantisation
+// configured by c (see PQStorage).
+func WithPQ(c PQConfig) Option {
+	return func(o *options) {
+		o.storage = PQStorage
+		o.pq = c
+	}
+}
+
+// ConvertPQ returns a Model with the vocabulary of m, but with vectors stored using
+// product quantisation configured by c (see PQStorage).  Training the codebooks reads
+// the vectors of m, which can be closed afterwards.
+func (m *Model) ConvertPQ(c PQConfig) *Model {
+	return m.convert(trainPQ(m.store, m.vocab.size(), m.dim, c))
+}
+
+// pqStorage stores each vector as a code for each sub-space, which indexes the codebook
+// of centroids for the sub-space.
+type pqStorage struct {
+	dim, m, k int
+	bounds    []int     // m+1 offsets of the sub-spaces in vectors
+	codebooks []float32 // centroids of sub-space j at k*bounds[j], each of size bounds[j+1]-bounds[j]
+	codes     []byte    // m codes for each vector
+}
+
+func newPQStorage(dim, m, k int) *pqStorage {
+	s := &pqStorage{
+		dim:       dim,
+		m:         m,
+		k:         k,
+		bounds:    make([]int, m+1),
+		codebooks: make([]float32, k*dim),
+	}
+	for j := range s.bounds {
+		s.bounds[j] = j * dim / m
+	}
+	return s
+}
+
+func (s *pqStorage) kind() Storage { return PQStorage }
+
+// centroid returns centroid c of sub-space j.
+func (s *pqStorage) centroid(j, c int) Vector {
+	d := s.bounds[j+1] - s.bounds[j]
+	off := s.k*s.bounds[j] + c*d
+	return Vector(s.codebooks[off : off+d : off+d])
+}
+
+func (s *pqStorage) vector(i int) Vector {
+	v := make(Vector, 0, s.dim)
+	for j, c := range s.codes[i*s.m : (i+1)*s.m] {
+		v = append(v, s.centroid(j, int(c))...)
+	}
+	return v
+}
+
+func (s *pqStorage) dot(v Vector, i int) float32 {
+	var out float32
+	for j, c := range s.codes[i*s.m : (i+1)*s.m] {
+		out += v[s.bounds[j]:s.bounds[j+1]].Dot(s.centroid(j, int(c)))
+	}
+	return out
+}
+
+// query returns a function which computes the dot product of v with the vector with
+// index i using a table of the dot products of v with each centroid (asymmetric distance
+// computation).
+func (s *pqStorage) query(v Vector) func(i int) float32 {
+	table := make([]float32, s.m*s.k)
+	for j := 0; j < s.m; j++ {
+		u := v[s.bounds[j]:s.bounds[j+1]]
+		for c := 0; c < s.k; c++ {
+			table[j*s.k+c] = u.Dot(s.centroid(j, c))
+		}
+	}
+	return func(i int) float32 {
+		var out float32
+		for j, c := range s.codes[i*s.m : (i+1)*s.m] {
+			out += table[j*s.k+int(c)]
+		}
+		return out
+	}
+}
+
+// trainPQ trains product quantisation codebooks on size vectors of dimension dim from
+// src, and encodes the vectors.
+func trainPQ(src storage, size, dim int, c PQConfig) *pqStorage {
+	if c.Subspaces <= 0 {
+		c.Subspaces = (dim + 3) / 4
+	}
+	if c.Subspaces > dim {
+		c.Subspaces = dim
+	}
+	if c.Centroids <= 0 || c.Centroids > 256 {
+		c.Centroids = 256
+	}
+	if c.Iterations <= 0 {
+		c.Iterations = 20
+	}
+	if c.Sample <= 0 {
+		c.Sample = 1 << 16
+	}
+	if c.Sample > size {
+		c.Sample = size
+	}
+	if c.Centroids > c.Sample {
+		c.Centroids = c.Sample
+	}
+	if c.Centroids == 0 {
+		c.Centroids = 1
+	}
+
+	s := newPQStorage(dim, c.Subspaces, c.Centroids)
+	s.codes = make([]byte, size*s.m)
+	if size == 0 {
+		return s
+	}
+
+	// Train on vectors spread evenly through the vocabulary.
+	sample := make([]Vector, c.Sample)
+	for i := range sample {
+		sample[i] = src.vector(i * size / c.Sample)
+	}
+
+	parallel(s.m, func(lo, hi int) {
+		for j := lo; j < hi; j++ {
+			r := rand.New(rand.NewSource(c.Seed + int64(j)))
+			s.train(j, sample, c.Iterations, r)
+		}
+	})
+
+	parallel(size, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			v := src.vector(i)
+			for j := 0; j < s.m; j++ {
+				s.codes[i*s.m+j] = byte(s.nearest(j, v[s.bounds[j]:s.bounds[j+1]]))
+			}
+		}
+	})
+	return s
+}
+
+// train runs k-means over sub-space j of the sample vectors to find its centroids.
+func (s *pqStorage) train(j int, sample []Vector, iterations int, r *rand.Rand) {
+	lo, hi := s.bounds[j], s.bounds[j+1]
+	for c, i := range r.Perm(len(sample))[:s.k] {
+		copy(s.centroid(j, c), sample[i][lo:hi])
+	}
+
+	assign := make([]int, len(sample))
+	counts := make([]int, s.k)
+	for it := 0; it < iterations; it++ {
+		changed := false
+		for i, v := range sample {
+			c := s.nearest(j, v[lo:hi])
+			if c != assign[i] || it == 0 {
+				changed = true
+			}
+			assign[i] = c
+		}
+		if !changed {
+			break
+		}
+
+		for c := range counts {
+			counts[c] = 0
+			u := s.centroid(j, c)
+			for x := range u {
+				u[x] = 0
+			}
+		}
+		for i, v := range sample {
+			s.centroid(j, assign[i]).Add(1, v[lo:hi])
+			counts[assign[i]]++
+		}
+		for c, n := range counts {
+			u := s.centroid(j, c)
+			if n == 0 {
+				// Reinitialise empty clusters to a random sample.
+				copy(u, sample[r.Intn(len(sample))][lo:hi])
+				continue
+			}
+			for x := range u {
+				u[x] /= float32(n)
+			}
+		}
+	}
+}
+
+// nearest returns the index of the centroid of sub-space j nearest to u.
+func (s *pqStorage) nearest(j int, u Vector) int {
+	best, min := 0, float32(math.Inf(1))
+	for c := 0; c < s.k; c++ {
+		var d float32
+		for x, y := range s.centroid(j, c) {
+			e := u[x] - y
+			d += e * e
+		}
+		if d < min {
+			best, min = c, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,65 @@
+package word2vec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPQ(t *testing.T) {
+	data := testRandomModelData(t, 2000, 32, 2)
+	ref, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	c := PQConfig{Subspaces: 16, Centroids: 64, Seed: 1}
+	m, err := FromReader(bytes.NewReader(data), WithPQ(c))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	if m.Storage() != PQStorage {
+		t.Errorf("m.Storage() = %v, expected %v", m.Storage(), PQStorage)
+	}
+	st := m.store.(*pqStorage)
+	if len(st.codes) != 2000*16 {
+		t.Errorf("len(codes) = %d, expected %d", len(st.codes), 2000*16)
+	}
+
+	// The asymmetric distance table gives the same scores as decoding the vectors.
+	v := ref.row(7)
+	dot := st.query(v)
+	for i := 0; i < 100; i++ {
+		if x, y := dot(i), v.Dot(st.vector(i)); !approxEqual(x, y) {
+			t.Errorf("query(v)(%d) = %v, expected %v", i, x, y)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := m.WriteMmap(buf); err != nil {
+		t.Fatalf("unexpected error from WriteMmap: %v", err)
+	}
+	mm, err := readMmap(buf)
+	if err != nil {
+		t.Fatalf("unexpected error from readMmap: %v", err)
+	}
+
+	queries := SampleQueries(ref, 100)
+	for _, c := range []*Model{m, ref.ConvertPQ(c), mm} {
+		r, err := CompareRankings(ref, c, queries, 10)
+		if err != nil {
+			t.Errorf("unexpected error from CompareRankings: %v", err)
+			continue
+		}
+		if r.Recall < 0.5 || r.Top1 < 0.9 {
+			t.Errorf("CompareRankings() = %v, expected recall@10 >= 0.5 and top-1 >= 0.9", r)
+		}
+	}
+
+	r, err := CompareRankings(m, mm, queries, 10)
+	if err != nil {
+		t.Fatalf("unexpected error from CompareRankings: %v", err)
+	}
+	if r.Recall != 1 || r.ScoreError != 0 {
+		t.Errorf("CompareRankings(m, mm) = %v, expected identical rankings", r)
+	}
+}
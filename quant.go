@@ -0,0 +1,121 @@
+package word2vec
+
+import "math"
+
+// Float16 is an IEEE 754 half-precision floating point number, used to store vectors
+// in half the memory of float32 (see Float16Storage).
+type Float16 uint16
+
+// ToFloat16 converts x to the nearest Float16 (rounding ties to even).  Values too large
+// to be represented become infinities.
+func ToFloat16(x float32) Float16 {
+	b := math.Float32bits(x)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127 + 15
+	mant := b & 0x7fffff
+
+	switch {
+	case b&0x7fffffff > 0x7f800000: // NaN
+		return Float16(sign | 0x7e00)
+	case exp >= 0x1f: // overflow or infinity
+		return Float16(sign | 0x7c00)
+	case exp <= 0: // subnormal or zero
+		if exp < -10 {
+			return Float16(sign)
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		h := mant >> shift
+		rem, half := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > half || (rem == half && h&1 == 1) {
+			h++
+		}
+		return Float16(sign | uint16(h))
+	}
+
+	// Rounding may carry into the exponent, which gives the correct result.
+	h := uint32(exp)<<10 | mant>>13
+	if rem := mant & 0x1fff; rem > 0x1000 || (rem == 0x1000 && h&1 == 1) {
+		h++
+	}
+	return Float16(sign | uint16(h))
+}
+
+// Float32 returns h as a float32, which is exact.
+func (h Float16) Float32() float32 {
+	return float16Table[h]
+}
+
+// float16Table maps each Float16 to its float32 value.
+var float16Table = func() (t [1 << 16]float32) {
+	for i := range t {
+		t[i] = float16ToFloat32(Float16(i))
+	}
+	return t
+}()
+
+func float16ToFloat32(h Float16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0x1f: // infinity or NaN
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0: // subnormal or zero
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// DotFloat16 computes the dot product with u.
+func (v Vector) DotFloat16(u []Float16) float32 {
+	var out float32
+	for i, vx := range v {
+		out += vx * float16Table[u[i]]
+	}
+	return out
+}
+
+// QuantiseInt8 quantises v into q (which must be the same length), scaling the values so
+// that the largest magnitude is 127.  Returns the scale s, so that v[i] ≈ s * q[i].
+func (v Vector) QuantiseInt8(q []int8) float32 {
+	var max float32
+	for _, x := range v {
+		if x < 0 {
+			x = -x
+		}
+		if x > max {
+			max = x
+		}
+	}
+	if max == 0 {
+		for i := range q {
+			q[i] = 0
+		}
+		return 0
+	}
+
+	s := max / 127
+	for i, x := range v {
+		q[i] = int8(math.Round(float64(x / s)))
+	}
+	return s
+}
+
+// DotInt8 computes the dot product with the quantised vector s * u (see QuantiseInt8).
+func (v Vector) DotInt8(u []int8, s float32) float32 {
+	var out float32
+	for i, vx := range v {
+		out += vx * float32(u[i])
+	}
+	return s * out
+}
@@ -0,0 +1,77 @@
+package word2vec
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloat16(t *testing.T) {
+	tests := []struct {
+		x float32
+		h Float16
+		y float32 // value of h
+	}{
+		{0, 0x0000, 0},
+		{1, 0x3c00, 1},
+		{-2, 0xc000, -2},
+		{0.5, 0x3800, 0.5},
+		{65504, 0x7bff, 65504},
+		{65520, 0x7c00, float32(math.Inf(1))},  // rounds up to infinity
+		{1.0009766, 0x3c01, 1.0009766},         // 1 + 2^-10
+		{1.0004883, 0x3c00, 1},                 // tie rounds to even
+		{1.0014648, 0x3c02, 1.0019531},         // tie rounds to even
+		{5.9604645e-08, 0x0001, 5.9604645e-08}, // smallest subnormal
+		{2.9802322e-08, 0x0000, 0},             // tie rounds to even (zero)
+		{6.1035156e-05, 0x0400, 6.1035156e-05}, // smallest normal
+		{float32(math.Inf(-1)), 0xfc00, float32(math.Inf(-1))},
+	}
+
+	for _, tt := range tests {
+		h := ToFloat16(tt.x)
+		if h != tt.h {
+			t.Errorf("ToFloat16(%v) = %#04x, expected %#04x", tt.x, uint16(h), uint16(tt.h))
+		}
+		if y := h.Float32(); y != tt.y {
+			t.Errorf("Float16(%#04x).Float32() = %v, expected %v", uint16(h), y, tt.y)
+		}
+	}
+
+	if y := ToFloat16(float32(math.NaN())).Float32(); !math.IsNaN(float64(y)) {
+		t.Errorf("ToFloat16(NaN).Float32() = %v, expected NaN", y)
+	}
+
+	// All finite values round trip.
+	for i := 0; i < 1<<16; i++ {
+		h := Float16(i)
+		if i&0x7c00 == 0x7c00 {
+			continue
+		}
+		if got := ToFloat16(h.Float32()); got != h && !(h == 0x8000 && got == 0x8000) {
+			t.Errorf("ToFloat16(%v) = %#04x, expected %#04x", h.Float32(), uint16(got), i)
+		}
+	}
+}
+
+func TestQuantiseInt8(t *testing.T) {
+	v := Vector{0.5, -1, 0.25, 0}
+	q := make([]int8, len(v))
+	s := v.QuantiseInt8(q)
+
+	expected := []int8{64, -127, 32, 0}
+	for i := range q {
+		if q[i] != expected[i] {
+			t.Errorf("q[%d] = %d, expected %d", i, q[i], expected[i])
+		}
+	}
+	if !approxEqual(s, 1.0/127) {
+		t.Errorf("scale = %v, expected %v", s, 1.0/127)
+	}
+
+	u := Vector{1, 1, 1, 1}
+	if d := u.DotInt8(q, s); math.Abs(float64(d-u.Dot(v))) > 0.01 {
+		t.Errorf("u.DotInt8() = %v, expected %v", d, u.Dot(v))
+	}
+	if d := u.DotFloat16([]Float16{ToFloat16(0.5), ToFloat16(-1), ToFloat16(0.25), 0}); d != -0.25 {
+		t.Errorf("u.DotFloat16() = %v, expected -0.25", d)
+	}
+}
@@ -0,0 +1,314 @@
+package word2vec
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// Projection is a type which represents a linear map of vectors to fewer dimensions,
+// computed from the vectors of a model by PCA or RandomProjection.  The vectors of a
+// model are those returned by Map: normalised, unless the model was loaded with
+// RawVectors.
+type Projection struct {
+	dim, k int
+	mean   []float32 // subtracted from vectors before projecting, nil if none
+	w      []float32 // dim×k matrix (row-major): projected vectors are (v - mean) w
+
+	// Variance is the fraction of the variance of the vectors of the model which lies in
+	// the subspace the projection maps onto (for PCA, the variance explained by the
+	// components).
+	Variance float64
+}
+
+// Dim returns the dimension of projected vectors.
+func (p *Projection) Dim() int {
+	return p.k
+}
+
+// Project returns the vector v mapped by the projection.  This can be used to project
+// vectors derived from the model (such as stored query or document vectors).  Panics if
+// v does not have the dimension of the model.
+func (p *Projection) Project(v Vector) Vector {
+	if len(v) != p.dim {
+		panic(fmt.Sprintf("vector has dimension %d, expected %d", len(v), p.dim))
+	}
+	out := make(Vector, p.k)
+	p.project(out, v, make(Vector, p.dim))
+	return out
+}
+
+// project sets out to v mapped by the projection, using buf (of the model dimension).
+func (p *Projection) project(out, v, buf Vector) {
+	if p.mean != nil {
+		copy(buf, v)
+		buf.Add(-1, p.mean)
+		v = buf
+	}
+	for j := range out {
+		out[j] = 0
+	}
+	for i, x := range v {
+		out.Add(x, Vector(p.w[i*p.k:(i+1)*p.k]))
+	}
+}
+
+// Apply returns a Model with the vocabulary of m (usually the model the projection was
+// computed from), whose vectors are the vectors of m mapped by the projection, stored as
+// float32.  The norms of the new model (see Model.Norm and DotProduct) are those of the
+// projected vectors.  The vectors are copied, so the model can be used after m is
+// closed.  Subword vectors (see Subwords) are not kept.  Returns an error if m does not
+// have the dimension of the projection.
+func (p *Projection) Apply(m *Model) (*Model, error) {
+	if m.dim != p.dim {
+		return nil, fmt.Errorf("cannot apply projection of dimension %d to model of dimension %d", p.dim, m.dim)
+	}
+	size := m.vocab.size()
+	st := &float32Storage{dim: p.k, vecs: make([]float32, size*p.k)}
+	norms := make([]float32, size)
+	parallel(size, func(lo, hi int) {
+		buf := make(Vector, p.dim)
+		for i := lo; i < hi; i++ {
+			v := st.row(i)
+			p.project(v, m.row(i), buf)
+			norms[i] = v.Norm()
+			if !m.raw && norms[i] != 0 {
+				v.Normalise()
+			}
+		}
+	})
+
+	c := *m
+	c.dim = p.k
+	c.sub = nil
+	c.norms = norms
+	return c.convert(st), nil
+}
+
+// PCAConfig is a type which configures principal component analysis (see PCA).  Zero
+// fields take their default values, except Dim which must be set.
+type PCAConfig struct {
+	// Dim is the number of principal components, which is the dimension of projected
+	// vectors.  It must be between 1 and the dimension of the model.
+	Dim int
+
+	// Iterations is the number of power iterations of the randomised SVD, each of which
+	// reads the vectors of the model once.  More iterations give more accurate
+	// components.  Defaults to 4.
+	Iterations int
+
+	// Oversample is the number of extra random directions used by the randomised SVD.
+	// Defaults to 10.
+	Oversample int
+
+	// Seed seeds the random starting directions.
+	Seed int64
+}
+
+// PCA computes the projection of the vectors of m onto their first c.Dim principal
+// components, after subtracting their mean.  The components are computed by randomised
+// SVD (subspace iteration from random starting directions), which reads the vectors of m
+// c.Iterations+2 times, and only needs memory for a few matrices of the model dimension.
+func PCA(m *Model, c PCAConfig) (*Projection, error) {
+	if c.Dim <= 0 || c.Dim > m.dim {
+		return nil, fmt.Errorf("invalid PCA dimension %d: must be between 1 and the model dimension %d", c.Dim, m.dim)
+	}
+	if c.Iterations <= 0 {
+		c.Iterations = 4
+	}
+	if c.Oversample <= 0 {
+		c.Oversample = 10
+	}
+	l := c.Dim + c.Oversample
+	if l > m.dim {
+		l = m.dim
+	}
+
+	mean, total := meanVariance(m)
+	r := rand.New(rand.NewSource(c.Seed))
+	z := make([]float64, m.dim*l)
+	for i := range z {
+		z[i] = r.NormFloat64()
+	}
+	svd(z, m.dim, l)
+	for it := 0; it < c.Iterations; it++ {
+		z = covarianceProduct(m, mean, z, l)
+		svd(z, m.dim, l)
+	}
+
+	// Rayleigh-Ritz: the eigenvectors of z^T C z (where C is the covariance of the
+	// vectors) rotate z onto the principal components within its span.
+	cz := covarianceProduct(m, mean, z, l)
+	a := make([]float64, l*l)
+	for x := 0; x < l; x++ {
+		for y := 0; y < l; y++ {
+			var sum float64
+			for i := 0; i < m.dim; i++ {
+				sum += z[i*l+x] * cz[i*l+y]
+			}
+			a[x*l+y] = sum
+		}
+	}
+	s, _ := svd(a, l, l)
+
+	p := &Projection{dim: m.dim, k: c.Dim, mean: make([]float32, m.dim), w: make([]float32, m.dim*c.Dim)}
+	for i, x := range mean {
+		p.mean[i] = float32(x)
+	}
+	for i := 0; i < m.dim; i++ {
+		for j := 0; j < c.Dim; j++ {
+			var sum float64
+			for k := 0; k < l; k++ {
+				sum += z[i*l+k] * a[k*l+j]
+			}
+			p.w[i*c.Dim+j] = float32(sum)
+		}
+	}
+	if total > 0 {
+		var retained float64
+		for _, x := range s[:c.Dim] {
+			retained += x
+		}
+		p.Variance = retained / total
+	}
+	return p, nil
+}
+
+// RandomProjectionConfig is a type which configures a random projection (see
+// RandomProjection).  Zero fields take their default values, except Dim which must be
+// set.
+type RandomProjectionConfig struct {
+	// Dim is the dimension of projected vectors, between 1 and the dimension of the
+	// model.
+	Dim int
+
+	// Sparse uses a sparse random matrix, whose entries are 0 with probability 2/3, and
+	// otherwise ±sqrt(3/Dim), rather than a Gaussian matrix with entries of variance
+	// 1/Dim.  Sparse projections are quicker to compute, and preserve distances as well.
+	Sparse bool
+
+	// Seed seeds the random matrix.
+	Seed int64
+}
+
+// RandomProjection returns a random projection of the vectors of m (which approximately
+// preserves the distances between them, by the Johnson-Lindenstrauss lemma), and reads
+// the vectors of m to compute the variance it retains.
+func RandomProjection(m *Model, c RandomProjectionConfig) (*Projection, error) {
+	if c.Dim <= 0 || c.Dim > m.dim {
+		return nil, fmt.Errorf("invalid projection dimension %d: must be between 1 and the model dimension %d", c.Dim, m.dim)
+	}
+	r := rand.New(rand.NewSource(c.Seed))
+	p := &Projection{dim: m.dim, k: c.Dim, w: make([]float32, m.dim*c.Dim)}
+	scale := 1 / math.Sqrt(float64(c.Dim))
+	for i := range p.w {
+		if !c.Sparse {
+			p.w[i] = float32(r.NormFloat64() * scale)
+			continue
+		}
+		switch r.Intn(6) {
+		case 0:
+			p.w[i] = float32(math.Sqrt(3) * scale)
+		case 1:
+			p.w[i] = -float32(math.Sqrt(3) * scale)
+		}
+	}
+
+	// The retained variance is that of the projection onto the span of the columns of w.
+	mean, total := meanVariance(m)
+	q := make([]float64, m.dim*c.Dim)
+	for i, x := range p.w {
+		q[i] = float64(x)
+	}
+	s, _ := svd(q, m.dim, c.Dim)
+	for j, x := range s {
+		if x == 0 {
+			// Columns completed by svd are not in the span of w.
+			for i := 0; i < m.dim; i++ {
+				q[i*c.Dim+j] = 0
+			}
+		}
+	}
+	if total > 0 {
+		cq := covarianceProduct(m, mean, q, c.Dim)
+		var retained float64
+		for i, x := range q {
+			retained += x * cq[i]
+		}
+		p.Variance = retained / total
+	}
+	return p, nil
+}
+
+// meanVariance returns the mean of the vectors of m, and the sum of their squared
+// distances from the mean (the trace of the covariance matrix, scaled by the size).
+func meanVariance(m *Model) ([]float64, float64) {
+	var mu sync.Mutex
+	mean := make([]float64, m.dim)
+	var sq float64
+	parallel(m.vocab.size(), func(lo, hi int) {
+		sum := make([]float64, m.dim)
+		var s float64
+		for i := lo; i < hi; i++ {
+			for j, x := range m.row(i) {
+				sum[j] += float64(x)
+				s += float64(x) * float64(x)
+			}
+		}
+		mu.Lock()
+		for j, x := range sum {
+			mean[j] += x
+		}
+		sq += s
+		mu.Unlock()
+	})
+
+	n := float64(m.vocab.size())
+	if n == 0 {
+		return mean, 0
+	}
+	var norm float64
+	for j := range mean {
+		mean[j] /= n
+		norm += mean[j] * mean[j]
+	}
+	return mean, math.Max(sq-n*norm, 0)
+}
+
+// covarianceProduct returns C z, where C is the sum over the vectors v of m of
+// (v - mean)^T (v - mean), and z is a dim×l matrix (row-major).
+func covarianceProduct(m *Model, mean, z []float64, l int) []float64 {
+	var mu sync.Mutex
+	out := make([]float64, m.dim*l)
+	parallel(m.vocab.size(), func(lo, hi int) {
+		acc := make([]float64, m.dim*l)
+		x := make([]float64, m.dim)
+		y := make([]float64, l)
+		for i := lo; i < hi; i++ {
+			for j, v := range m.row(i) {
+				x[j] = float64(v) - mean[j]
+			}
+			for k := range y {
+				y[k] = 0
+			}
+			for j, xj := range x {
+				for k, zk := range z[j*l : (j+1)*l] {
+					y[k] += xj * zk
+				}
+			}
+			for j, xj := range x {
+				row := acc[j*l : (j+1)*l]
+				for k, yk := range y {
+					row[k] += xj * yk
+				}
+			}
+		}
+		mu.Lock()
+		for i, v := range acc {
+			out[i] += v
+		}
+		mu.Unlock()
+	})
+	return out
+}
@@ -0,0 +1,166 @@
+package word2vec
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testLowRankModel returns a model of size words whose vectors (of dimension dim) are
+// close to a subspace of dimension rank, with decreasing variance along its axes.
+func testLowRankModel(t *testing.T, size, dim, rank int) *Model {
+	r := rand.New(rand.NewSource(2))
+	basis := make([]Vector, rank)
+	for k := range basis {
+		basis[k] = make(Vector, dim)
+		for j := range basis[k] {
+			basis[k][j] = float32(r.NormFloat64())
+		}
+	}
+	data := testRandomModelData(t, size, dim, 1, func(r *rand.Rand, v Vector) {
+		for j := range v {
+			v[j] *= 0.01
+		}
+		for k, b := range basis {
+			v.Add(float32(r.NormFloat64())/float32(k+1), b)
+		}
+	})
+	m, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	return m
+}
+
+// testRetainedVariance returns the exact fraction of the variance of the vectors of m
+// explained by the first k principal components.
+func testRetainedVariance(m *Model, k int) float64 {
+	mean, total := meanVariance(m)
+	c := make([]float64, m.dim*m.dim)
+	for i := 0; i < m.dim; i++ {
+		e := make([]float64, m.dim)
+		e[i] = 1
+		copy(c[i*m.dim:], covarianceProduct(m, mean, e, 1))
+	}
+	s, _ := svd(c, m.dim, m.dim)
+	var retained float64
+	for _, x := range s[:k] {
+		retained += x
+	}
+	return retained / total
+}
+
+func TestPCA(t *testing.T) {
+	m := testLowRankModel(t, 500, 12, 4)
+	var last float64
+	for _, k := range []int{1, 2, 4, 12} {
+		p, err := PCA(m, PCAConfig{Dim: k})
+		if err != nil {
+			t.Fatalf("[%d] unexpected error from PCA: %v", k, err)
+		}
+		if p.Dim() != k {
+			t.Errorf("[%d] Dim() = %d, expected %d", k, p.Dim(), k)
+		}
+		if expected := testRetainedVariance(m, k); math.Abs(p.Variance-expected) > 1e-6 {
+			t.Errorf("[%d] Variance = %v, expected %v", k, p.Variance, expected)
+		}
+		if p.Variance < last {
+			t.Errorf("[%d] Variance = %v, expected at least %v", k, p.Variance, last)
+		}
+		last = p.Variance
+
+		reduced, err := p.Apply(m)
+		if err != nil {
+			t.Fatalf("[%d] unexpected error from Apply: %v", k, err)
+		}
+		if reduced.Dim() != k || reduced.Size() != m.Size() {
+			t.Errorf("[%d] reduced model dim %d, size %d, expected dim %d, size %d", k, reduced.Dim(), reduced.Size(), k, m.Size())
+		}
+		for _, w := range []string{"aaa", "kba"} {
+			u := p.Project(m.Map([]string{w})[w])
+			v := reduced.Map([]string{w})[w]
+			if n, _ := reduced.Norm(w); !approxEqual(n, u.Norm()) {
+				t.Errorf("[%d] Norm(%q) = %v, expected %v", k, w, n, u.Norm())
+			}
+			u.Normalise()
+			if !approxEqual(u.Dot(v), 1) {
+				t.Errorf("[%d] reduced vector for %q = %v, expected %v", k, w, v, u)
+			}
+		}
+	}
+	if last < 0.999 {
+		t.Errorf("Variance = %v with all components, expected 1", last)
+	}
+}
+
+func TestRandomProjection(t *testing.T) {
+	m := testLowRankModel(t, 500, 12, 4)
+	random, err := FromReader(bytes.NewReader(testRandomModelData(t, 500, 32, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	for _, sparse := range []bool{false, true} {
+		p, err := RandomProjection(m, RandomProjectionConfig{Dim: 6, Sparse: sparse, Seed: 1})
+		if err != nil {
+			t.Fatalf("[sparse %v] unexpected error from RandomProjection: %v", sparse, err)
+		}
+		if p.Variance <= 0 || p.Variance >= 1 {
+			t.Errorf("[sparse %v] Variance = %v, expected between 0 and 1", sparse, p.Variance)
+		}
+
+		reduced, err := p.Apply(m)
+		if err != nil {
+			t.Fatalf("[sparse %v] unexpected error from Apply: %v", sparse, err)
+		}
+		if reduced.Dim() != 6 {
+			t.Errorf("[sparse %v] reduced model dim %d, expected 6", sparse, reduced.Dim())
+		}
+
+		// Distances are approximately preserved on average.
+		p, err = RandomProjection(random, RandomProjectionConfig{Dim: 16, Sparse: sparse, Seed: 1})
+		if err != nil {
+			t.Fatalf("[sparse %v] unexpected error from RandomProjection: %v", sparse, err)
+		}
+		var ratio float64
+		for i := 0; i < 100; i++ {
+			u, _ := random.VectorByID(i)
+			v, _ := random.VectorByID(i + 100)
+			d := append(Vector(nil), u...)
+			d.Add(-1, v)
+			ratio += float64(p.Project(d).Norm()/d.Norm()) / 100
+		}
+		if ratio < 0.8 || ratio > 1.2 {
+			t.Errorf("[sparse %v] mean ratio of projected to original distance %v, expected about 1", sparse, ratio)
+		}
+
+		// A projection to the full dimension retains all the variance.
+		p, err = RandomProjection(m, RandomProjectionConfig{Dim: 12, Sparse: sparse, Seed: 1})
+		if err != nil {
+			t.Fatalf("[sparse %v] unexpected error from RandomProjection: %v", sparse, err)
+		}
+		if p.Variance < 0.999 || p.Variance > 1.001 {
+			t.Errorf("[sparse %v] Variance = %v with full dimension, expected 1", sparse, p.Variance)
+		}
+	}
+}
+
+func TestProjectionErrors(t *testing.T) {
+	m := testLowRankModel(t, 50, 6, 2)
+	for _, k := range []int{0, 7} {
+		if _, err := PCA(m, PCAConfig{Dim: k}); err == nil {
+			t.Errorf("PCA() with Dim %d returned nil error", k)
+		}
+		if _, err := RandomProjection(m, RandomProjectionConfig{Dim: k}); err == nil {
+			t.Errorf("RandomProjection() with Dim %d returned nil error", k)
+		}
+	}
+
+	p, err := PCA(m, PCAConfig{Dim: 2})
+	if err != nil {
+		t.Fatalf("unexpected error from PCA: %v", err)
+	}
+	if _, err := p.Apply(testLowRankModel(t, 50, 5, 2)); err == nil {
+		t.Errorf("Apply() to model with different dimension returned nil error")
+	}
+}
@@ -0,0 +1,210 @@
+package word2vec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Lexicon is a type which represents a graph of related words (such as synonyms), used
+// by Retrofit.  Edges are undirected and weighted.
+type Lexicon struct {
+	edges     [][2]string
+	neighbors map[string]map[string]float32
+}
+
+// NewLexicon returns an empty Lexicon.
+func NewLexicon() *Lexicon {
+	return &Lexicon{neighbors: make(map[string]map[string]float32)}
+}
+
+// ReadLexicon returns a Lexicon which is loaded with edges from r: a line for each edge,
+// with the two related words and an optional positive weight (which defaults to 1),
+// separated by whitespace.  Blank lines and lines starting with # are ignored.
+func ReadLexicon(r io.Reader) (*Lexicon, error) {
+	l := NewLexicon()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("[line: %d] expected 2 or 3 fields, instead got: %v", line, len(fields))
+		}
+		weight := 1.0
+		if len(fields) == 3 {
+			var err error
+			weight, err = strconv.ParseFloat(fields[2], 32)
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("[line: %d] invalid weight %#v: must be a positive number", line, fields[2])
+			}
+		}
+		l.Add(fields[0], fields[1], float32(weight))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("[line: %d] scanner error: %v", line+1, err)
+	}
+	return l, nil
+}
+
+// Add adds an edge between the words a and b with the given weight.  The weights of
+// repeated edges are summed.  Edges from a word to itself are ignored.
+func (l *Lexicon) Add(a, b string, weight float32) {
+	if a == b {
+		return
+	}
+	if _, ok := l.neighbors[a][b]; !ok {
+		l.edges = append(l.edges, [2]string{a, b})
+	}
+	for _, e := range [][2]string{{a, b}, {b, a}} {
+		n, ok := l.neighbors[e[0]]
+		if !ok {
+			n = make(map[string]float32)
+			l.neighbors[e[0]] = n
+		}
+		n[e[1]] += weight
+	}
+}
+
+// Len returns the number of words in the lexicon.
+func (l *Lexicon) Len() int {
+	return len(l.neighbors)
+}
+
+// Edges returns the pairs of related words in the lexicon, in the order they were added.
+func (l *Lexicon) Edges() [][2]string {
+	return append([][2]string(nil), l.edges...)
+}
+
+// RetrofitConfig is a type which configures retrofitting (see Retrofit).  Zero fields
+// take their default values.
+type RetrofitConfig struct {
+	// Iterations is the number of updates of the vectors.  Defaults to 10.
+	Iterations int
+
+	// Alpha is the weight which keeps each vector close to its original.  Defaults to 1.
+	Alpha float32
+
+	// Beta is the total weight which moves each vector towards those of its related
+	// words, which is shared between them in proportion to the weights of their edges.
+	// Defaults to 1.
+	Beta float32
+}
+
+// Retrofit returns a Model with the vocabulary of m, whose vectors are retrofitted to the
+// lexicon l (Faruqui et al., 2015): the vectors of related words in l are moved closer to
+// each other, while staying close to their original vectors.  Each iteration replaces
+// the vector of each word in l with the weighted mean of its original vector (with weight
+// c.Alpha) and the current vectors of its related words (with total weight c.Beta).
+// Words of l which are not in m are ignored, and the vectors of words of m which are not
+// in l are unchanged.
+//
+// The vectors are stored as float32, and are normalised unless m was loaded with
+// RawVectors.  The norms of the new model (see Model.Norm and DotProduct) are those of m,
+// scaled by the change in norm of the retrofitted vectors.  The vectors are copied, so the
+// model can be used after m is closed.  Subword vectors (see Subwords) are kept.  Returns
+// an error if no pair of related words in l is in m.
+func Retrofit(m *Model, l *Lexicon, c RetrofitConfig) (*Model, error) {
+	if c.Iterations <= 0 {
+		c.Iterations = 10
+	}
+	if c.Alpha <= 0 {
+		c.Alpha = 1
+	}
+	if c.Beta <= 0 {
+		c.Beta = 1
+	}
+
+	// The related words of each word of l in m, with their edge weights scaled to sum to
+	// c.Beta.
+	type edge struct {
+		j int
+		w float32
+	}
+	var ids []int
+	var edges [][]edge
+	index := make(map[int]int) // index in ids of each word ID
+	for _, e := range l.edges {
+		a, ok := m.vocab.id(e[0])
+		if !ok {
+			continue
+		}
+		b, ok := m.vocab.id(e[1])
+		if !ok {
+			continue
+		}
+		w := l.neighbors[e[0]][e[1]]
+		for _, p := range [][2]int{{a, b}, {b, a}} {
+			k, ok := index[p[0]]
+			if !ok {
+				k = len(ids)
+				index[p[0]] = k
+				ids = append(ids, p[0])
+				edges = append(edges, nil)
+			}
+			edges[k] = append(edges[k], edge{p[1], w})
+		}
+	}
+	for _, es := range edges {
+		var total float32
+		for _, e := range es {
+			total += e.w
+		}
+		for k := range es {
+			es[k].w *= c.Beta / total
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no related words of the lexicon are in the model")
+	}
+
+	size := m.vocab.size()
+	st := &float32Storage{dim: m.dim, vecs: make([]float32, size*m.dim)}
+	parallel(size, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			copy(st.row(i), m.row(i))
+		}
+	})
+	next := make([]float32, len(ids)*m.dim)
+	for it := 0; it < c.Iterations; it++ {
+		parallel(len(ids), func(lo, hi int) {
+			for k := lo; k < hi; k++ {
+				v := Vector(next[k*m.dim : (k+1)*m.dim])
+				for j := range v {
+					v[j] = 0
+				}
+				v.Add(c.Alpha/(c.Alpha+c.Beta), m.row(ids[k]))
+				for _, e := range edges[k] {
+					v.Add(e.w/(c.Alpha+c.Beta), st.row(e.j))
+				}
+			}
+		})
+		for k, i := range ids {
+			copy(st.row(i), next[k*m.dim:(k+1)*m.dim])
+		}
+	}
+
+	norms := make([]float32, size)
+	for i := range norms {
+		norms[i] = m.norm(i)
+	}
+	for _, i := range ids {
+		v := st.row(i)
+		n := v.Norm()
+		if orig := m.row(i).Norm(); orig != 0 {
+			norms[i] *= n / orig
+		}
+		if !m.raw && n != 0 {
+			v.Normalise()
+		}
+	}
+
+	r := *m
+	r.norms = norms
+	return r.convert(st), nil
+}
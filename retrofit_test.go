@@ -0,0 +1,118 @@
+package word2vec
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadLexicon(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		edges [][2]string
+		len   int
+		err   bool
+	}{
+		{
+			name:  "edges",
+			data:  "# synonyms\ncar auto\n\ncar vehicle 0.5\nauto car\ncar car\n",
+			edges: [][2]string{{"car", "auto"}, {"car", "vehicle"}},
+			len:   3,
+		},
+		{name: "fields", data: "car auto\ncar\n", err: true},
+		{name: "weight", data: "car auto x\n", err: true},
+		{name: "negative weight", data: "car auto -1\n", err: true},
+	}
+
+	for _, tt := range tests {
+		l, err := ReadLexicon(strings.NewReader(tt.data))
+		if tt.err {
+			if err == nil {
+				t.Errorf("[%s] ReadLexicon() returned nil error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error from ReadLexicon: %v", tt.name, err)
+			continue
+		}
+		if edges := l.Edges(); !reflect.DeepEqual(edges, tt.edges) {
+			t.Errorf("[%s] Edges() = %v, expected %v", tt.name, edges, tt.edges)
+		}
+		if l.Len() != tt.len {
+			t.Errorf("[%s] Len() = %d, expected %d", tt.name, l.Len(), tt.len)
+		}
+	}
+}
+
+func TestRetrofit(t *testing.T) {
+	words := []string{"car", "auto", "dog", "cat"}
+	vecs := []Vector{
+		{1, 0, 0},
+		{0, 1, 0},
+		{0, 0, 1},
+		{0.6, 0, 0.8},
+	}
+	data := testModelData(t, words, vecs)
+
+	l := NewLexicon()
+	l.Add("car", "auto", 1)
+	l.Add("car", "truck", 1) // not in the model
+
+	m, err := FromReader(bytes.NewReader(data), RawVectors())
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	r, err := Retrofit(m, l, RetrofitConfig{Iterations: 20})
+	if err != nil {
+		t.Fatalf("unexpected error from Retrofit: %v", err)
+	}
+
+	// With Alpha = Beta = 1, the vectors converge to q(car) = (2 car + auto)/3 and
+	// q(auto) = (2 auto + car)/3.
+	expected := map[string]Vector{
+		"car":  {2.0 / 3, 1.0 / 3, 0},
+		"auto": {1.0 / 3, 2.0 / 3, 0},
+		"dog":  {0, 0, 1},
+		"cat":  {0.6, 0, 0.8},
+	}
+	got := r.Map(words)
+	for w, e := range expected {
+		for j := range e {
+			if math.Abs(float64(got[w][j]-e[j])) > 1e-4 {
+				t.Errorf("retrofitted vector for %q = %v, expected %v", w, got[w], e)
+				break
+			}
+		}
+		if n, _ := r.Norm(w); !approxEqual(n, e.Norm()) {
+			t.Errorf("Norm(%q) = %v, expected %v", w, n, e.Norm())
+		}
+	}
+
+	// Normalised models stay normalised, and related words move closer.
+	m, err = FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	r, err = Retrofit(m, l, RetrofitConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error from Retrofit: %v", err)
+	}
+	before, _ := m.Cos(Expr{"car": 1}, Expr{"auto": 1})
+	after, _ := r.Cos(Expr{"car": 1}, Expr{"auto": 1})
+	if after <= before {
+		t.Errorf("Cos(car, auto) = %v after retrofitting, expected more than %v", after, before)
+	}
+	if v := r.Map([]string{"car"})["car"]; !approxEqual(v.Norm(), 1) {
+		t.Errorf("norm of retrofitted vector = %v, expected 1", v.Norm())
+	}
+
+	l = NewLexicon()
+	l.Add("car", "truck", 1)
+	if _, err := Retrofit(m, l, RetrofitConfig{}); err == nil {
+		t.Errorf("Retrofit() with no related words in the model returned nil error")
+	}
+}
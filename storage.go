@@ -0,0 +1,201 @@
+package word2vec
+
+import "fmt"
+
+// Storage is a type which represents how the vectors of a Model are stored in memory.
+// Reduced-precision storage uses less memory at the cost of some accuracy (see
+// CompareRankings).
+type Storage int
+
+// Storage types.
+const (
+	// Float32Storage stores vectors as float32, as in the model data.  This is the
+	// default.
+	Float32Storage Storage = iota
+
+	// Float16Storage stores vectors as half-precision floats (see Float16), using half
+	// the memory of Float32Storage.
+	Float16Storage
+
+	// Int8Storage stores each vector as int8 values with a float32 scale (see
+	// Vector.QuantiseInt8), using about a quarter of the memory of Float32Storage.
+	Int8Storage
+
+	// PQStorage stores vectors using product quantisation: the vectors are split into
+	// sub-spaces, and each part is stored as a byte which indexes a codebook of centroids
+	// trained for the sub-space using k-means (see PQConfig).  CosN compares the
+	// expression vector with the centroids once, and then scores each word by summing
+	// the results for its codes.
+	PQStorage
+)
+
+func (s Storage) String() string {
+	switch s {
+	case Float32Storage:
+		return "float32"
+	case Float16Storage:
+		return "float16"
+	case Int8Storage:
+		return "int8"
+	case PQStorage:
+		return "pq"
+	}
+	return fmt.Sprintf("Storage(%d)", int(s))
+}
+
+// WithStorage is an Option which stores the vectors of the model using s.  PQStorage
+// uses the default PQConfig (see WithPQ).
+func WithStorage(s Storage) Option {
+	return func(o *options) {
+		o.storage = s
+	}
+}
+
+// Storage returns how the vectors of the model are stored.
+func (m *Model) Storage() Storage {
+	return m.store.kind()
+}
+
+// Convert returns a Model with the vocabulary of m, but with vectors stored using s.
+// The vectors are copied, so the model can be used after m is closed.  PQStorage uses
+// the default PQConfig (see ConvertPQ).
+func (m *Model) Convert(s Storage) *Model {
+	return m.convert(newStorage(s, m.store, m.vocab.size(), m.dim))
+}
+
+// convert returns a Model with the vocabulary of m and vectors stored in st.
+func (m *Model) convert(st storage) *Model {
+	c := *m
+	c.store = st
+	c.closer = nil
+	if m.norms != nil {
+		c.norms = append([]float32(nil), m.norms...)
+	}
+	if _, ok := m.vocab.(*mapVocab); !ok {
+		v := newMapVocab(m.vocab.size())
+		for i := 0; i < m.vocab.size(); i++ {
+			v.add(m.vocab.word(i))
+		}
+		c.vocab = v
+	}
+	c.fp = fingerprint(&c)
+	return &c
+}
+
+// storage is an interface which defines how the vectors of a Model are stored.
+type storage interface {
+	// kind returns the Storage type.
+	kind() Storage
+
+	// vector returns the vector with index i.  The vector is shared with the storage
+	// if possible, otherwise it is decoded into a new Vector.
+	vector(i int) Vector
+
+	// dot returns the dot product of v with the vector with index i.
+	dot(v Vector, i int) float32
+}
+
+// queryStorage is an interface implemented by storage which can compute the dot products
+// of a vector with many stored vectors more efficiently than by calling dot.
+type queryStorage interface {
+	storage
+
+	// query returns a function which computes the dot product of v with the vector
+	// with index i.
+	query(v Vector) func(i int) float32
+}
+
+// newStorage converts size vectors of dimension dim from src into storage of type s.
+func newStorage(s Storage, src storage, size, dim int) storage {
+	switch s {
+	case Float16Storage:
+		st := &float16Storage{dim: dim, vecs: make([]Float16, size*dim)}
+		parallel(size, func(lo, hi int) {
+			for i := lo; i < hi; i++ {
+				for j, x := range src.vector(i) {
+					st.vecs[i*dim+j] = ToFloat16(x)
+				}
+			}
+		})
+		return st
+
+	case Int8Storage:
+		st := &int8Storage{dim: dim, vecs: make([]int8, size*dim), scales: make([]float32, size)}
+		parallel(size, func(lo, hi int) {
+			for i := lo; i < hi; i++ {
+				st.scales[i] = src.vector(i).QuantiseInt8(st.vecs[i*dim : (i+1)*dim])
+			}
+		})
+		return st
+
+	case PQStorage:
+		return trainPQ(src, size, dim, PQConfig{})
+	}
+
+	st := &float32Storage{dim: dim, vecs: make([]float32, size*dim)}
+	for i := 0; i < size; i++ {
+		copy(st.row(i), src.vector(i))
+	}
+	return st
+}
+
+// float32Storage stores vectors contiguously as float32.
+type float32Storage struct {
+	dim  int
+	vecs []float32
+}
+
+func (s *float32Storage) kind() Storage { return Float32Storage }
+
+func (s *float32Storage) row(i int) Vector {
+	return Vector(s.vecs[i*s.dim : (i+1)*s.dim : (i+1)*s.dim])
+}
+
+func (s *float32Storage) vector(i int) Vector {
+	return s.row(i)
+}
+
+func (s *float32Storage) dot(v Vector, i int) float32 {
+	return v.Dot(s.row(i))
+}
+
+// float16Storage stores vectors contiguously as Float16.
+type float16Storage struct {
+	dim  int
+	vecs []Float16
+}
+
+func (s *float16Storage) kind() Storage { return Float16Storage }
+
+func (s *float16Storage) vector(i int) Vector {
+	v := make(Vector, s.dim)
+	for j, x := range s.vecs[i*s.dim : (i+1)*s.dim] {
+		v[j] = x.Float32()
+	}
+	return v
+}
+
+func (s *float16Storage) dot(v Vector, i int) float32 {
+	return v.DotFloat16(s.vecs[i*s.dim : (i+1)*s.dim])
+}
+
+// int8Storage stores vectors contiguously as int8, with a scale for each vector.
+type int8Storage struct {
+	dim    int
+	vecs   []int8
+	scales []float32
+}
+
+func (s *int8Storage) kind() Storage { return Int8Storage }
+
+func (s *int8Storage) vector(i int) Vector {
+	v := make(Vector, s.dim)
+	for j, x := range s.vecs[i*s.dim : (i+1)*s.dim] {
+		v[j] = s.scales[i] * float32(x)
+	}
+	return v
+}
+
+func (s *int8Storage) dot(v Vector, i int) float32 {
+	return v.DotInt8(s.vecs[i*s.dim:(i+1)*s.dim], s.scales[i])
+}
@@ -0,0 +1,109 @@
+package word2vec
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestSampleQueries(t *testing.T) {
+	m, err := FromReader(bytes.NewReader(testRandomModelData(t, 10, 4, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	empty, err := FromReader(bytes.NewReader([]byte("0 4\n")))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		m    *Model
+		k    int
+		len  int
+	}{
+		{name: "sample", m: m, k: 5, len: 5},
+		{name: "all", m: m, k: 20, len: 10},
+		{name: "zero", m: m, k: 0, len: 0},
+		{name: "negative", m: m, k: -1, len: 0},
+		{name: "empty", m: empty, k: 5, len: 0},
+		{name: "empty zero", m: empty, k: 0, len: 0},
+	}
+	for _, tt := range tests {
+		queries := SampleQueries(tt.m, tt.k)
+		if len(queries) != tt.len {
+			t.Errorf("[%s] SampleQueries() returned %d queries, expected %d", tt.name, len(queries), tt.len)
+		}
+		if tt.len == 0 && queries != nil {
+			t.Errorf("[%s] SampleQueries() = %v, expected nil", tt.name, queries)
+		}
+	}
+}
+
+func TestStorage(t *testing.T) {
+	data := testRandomModelData(t, 500, 32, 1)
+	ref, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	queries := SampleQueries(ref, 50)
+
+	tests := []struct {
+		storage Storage
+		recall  float64 // minimum recall@10
+		err     float32 // maximum error in vector components
+	}{
+		{Float32Storage, 1, 0},
+		{Float16Storage, 0.95, 1e-3},
+		{Int8Storage, 0.9, 1e-2},
+	}
+
+	for _, tt := range tests {
+		m, err := FromReader(bytes.NewReader(data), WithStorage(tt.storage))
+		if err != nil {
+			t.Errorf("[%v] unexpected error from FromReader: %v", tt.storage, err)
+			continue
+		}
+		if m.Storage() != tt.storage {
+			t.Errorf("[%v] m.Storage() = %v, expected %v", tt.storage, m.Storage(), tt.storage)
+		}
+
+		// Check the model, the converted model and the model written to and read from the
+		// mapped format.
+		buf := &bytes.Buffer{}
+		if err := m.WriteMmap(buf); err != nil {
+			t.Errorf("[%v] unexpected error from WriteMmap: %v", tt.storage, err)
+			continue
+		}
+		mm, err := readMmap(buf)
+		if err != nil {
+			t.Errorf("[%v] unexpected error from readMmap: %v", tt.storage, err)
+			continue
+		}
+
+		for _, c := range []*Model{m, ref.Convert(tt.storage), mm} {
+			if c.Storage() != tt.storage {
+				t.Errorf("[%v] c.Storage() = %v, expected %v", tt.storage, c.Storage(), tt.storage)
+			}
+			c.Range(func(id int, w string, v Vector) bool {
+				u := ref.row(id)
+				for j := range v {
+					if d := math.Abs(float64(v[j] - u[j])); float32(d) > tt.err {
+						t.Errorf("[%v] vector for %q = %v, expected %v", tt.storage, w, v, u)
+						return false
+					}
+				}
+				return true
+			})
+
+			r, err := CompareRankings(ref, c, queries, 10)
+			if err != nil {
+				t.Errorf("[%v] unexpected error from CompareRankings: %v", tt.storage, err)
+				continue
+			}
+			if r.Recall < tt.recall || r.Top1 != 1 {
+				t.Errorf("[%v] CompareRankings() = %v, expected recall@10 >= %v and top-1 1", tt.storage, r, tt.recall)
+			}
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package word2vec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// batchSize is the number of entries read from model data in each batch.
+const batchSize = 1024
+
+// batch is a group of consecutive entries read from model data, which are decoded
+// in parallel with other batches.
+type batch struct {
+	first int      // index of the first entry
+	offs  []int64  // offset of each entry
+	words []string // words (binary data), or set by decoding (text data)
+	data  []byte   // raw vectors (binary data)
+	lines []string // lines (text data)
+	end   int64    // offset after the last entry
+
+	vecs []float32 // decoded vectors
+
+	readErr error         // error reading the entry after the batch
+	err     error         // error decoding the batch
+	done    chan struct{} // closed once the batch is decoded
+}
+
+func (b *batch) len() int {
+	return len(b.offs)
+}
+
+// stream reads the entries of model data in batches using read, decodes them in parallel
+// using decode, and adds them to bld in order.  The header must already have been read.
+// read should return io.EOF once there are no more entries.
+func (p *parser) stream(ctx context.Context, bld *builder, size int, read func(*batch) error, decode func(*batch)) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	workers := runtime.GOMAXPROCS(0)
+	work := make(chan *batch, workers)
+	ordered := make(chan *batch, 2*workers)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for b := range work {
+				decode(b)
+				close(b.done)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(ordered)
+		defer close(work)
+		for {
+			b := &batch{
+				first: p.entry + 1,
+				done:  make(chan struct{}),
+			}
+			err := read(b)
+			if err != nil && err != io.EOF {
+				b.readErr = err
+			}
+
+			if b.len() > 0 {
+				select {
+				case work <- b:
+				case <-ctx.Done():
+					return
+				}
+			} else {
+				close(b.done)
+			}
+
+			select {
+			case ordered <- b:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		// Stop reading, and wait for the reader and decoders to finish so that the
+		// underlying reader is no longer in use.
+		cancel()
+		for range ordered {
+		}
+		wg.Wait()
+	}()
+
+	n := 0
+	for b := range ordered {
+		select {
+		case <-b.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if b.err != nil {
+			return b.err
+		}
+
+		dim := bld.dim
+		for i, w := range b.words {
+			if err := bld.add(w, b.vecs[i*dim:(i+1)*dim]); err != nil {
+				return &ParseError{Entry: b.first + i, Offset: b.offs[i], Err: fmt.Errorf("%w: %q", err, w)}
+			}
+			if bld.full() {
+				return nil
+			}
+		}
+		n += b.len()
+
+		if p.o.progress != nil {
+			p.o.progress(Progress{
+				Entries:    n,
+				Size:       size,
+				Bytes:      b.end,
+				TotalBytes: p.o.dataSize,
+			})
+		}
+
+		if b.readErr != nil {
+			return b.readErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBinary returns a function which reads batches of entries from binary model data
+// with size entries of dimension dim.
+func (p *parser) readBinary(size, dim int) func(*batch) error {
+	n := 4 * dim
+	return func(b *batch) error {
+		for b.len() < batchSize {
+			if p.entry+1 == size {
+				return io.EOF
+			}
+			p.next()
+			w, err := p.binaryWord()
+			if err != nil {
+				return err
+			}
+
+			k := len(b.data)
+			if cap(b.data) < k+n {
+				data := make([]byte, k, batchSize*n)
+				copy(data, b.data)
+				b.data = data
+			}
+			b.data = b.data[:k+n]
+			m, err := io.ReadFull(p.br, b.data[k:])
+			p.off += int64(m)
+			if err != nil {
+				b.data = b.data[:k]
+				return p.error(err)
+			}
+
+			b.offs = append(b.offs, p.start)
+			b.words = append(b.words, w)
+			b.end = p.off
+		}
+		return nil
+	}
+}
+
+// decodeBinary decodes the raw vectors of a batch of binary model data.
+func decodeBinary(dim int) func(*batch) {
+	return func(b *batch) {
+		b.vecs = make([]float32, b.len()*dim)
+		for i := 0; i < b.len(); i++ {
+			v := Vector(b.vecs[i*dim : (i+1)*dim])
+			if err := decodeVector(b.data[4*i*dim:4*(i+1)*dim], v); err != nil {
+				b.err = &ParseError{Entry: b.first + i, Offset: b.offs[i], Err: err}
+				return
+			}
+		}
+	}
+}
+
+// readText returns a function which reads batches of lines from text model data with
+// size entries (or -1 if the size is not known).  If first is not empty, it is the line
+// of the first entry, which has already been read.  Non-blank lines after size entries
+// are an error.
+func (p *parser) readText(size, max int, first string) func(*batch) error {
+	start := p.start
+	return func(b *batch) error {
+		for b.len() < batchSize {
+			if p.entry+1 == size {
+				p.next()
+				if _, err := p.line(max); err != nil {
+					return err
+				}
+				return p.errorf("expected %d entries, got more", size)
+			}
+
+			p.next()
+			line := first
+			if line != "" {
+				first, p.start = "", start
+			} else {
+				var err error
+				line, err = p.line(max)
+				if err == io.EOF && size >= 0 {
+					return p.errorf("expected %d entries, got %d: %w", size, p.entry, io.ErrUnexpectedEOF)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			if size < 0 && p.entry == p.o.limits.Size {
+				return p.errorf("too many entries (limit %d)", p.o.limits.Size)
+			}
+
+			b.offs = append(b.offs, p.start)
+			b.lines = append(b.lines, line)
+			b.end = p.off
+		}
+		return nil
+	}
+}
+
+// decodeText parses the lines of a batch of text model data.
+func decodeText(dim, maxWord int) func(*batch) {
+	return func(b *batch) {
+		b.vecs = make([]float32, b.len()*dim)
+		b.words = make([]string, b.len())
+		for i, line := range b.lines {
+			w, err := parseTextEntry(line, Vector(b.vecs[i*dim:(i+1)*dim]), maxWord)
+			if err != nil {
+				b.err = &ParseError{Entry: b.first + i, Offset: b.offs[i], Err: err}
+				return
+			}
+			b.words[i] = w
+		}
+	}
+}
+
+// parallel calls f over the range [0, n), split into contiguous parts which are processed
+// concurrently.
+func parallel(n int, f func(lo, hi int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(lo, hi int) {
+			defer wg.Done()
+			f(lo, hi)
+		}(i*n/workers, (i+1)*n/workers)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,90 @@
+package word2vec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestLoadContext(t *testing.T) {
+	n := 5*batchSize + 3
+	var vecs []Vector
+	bin := testRandomModelData(t, n, 3, 1, func(_ *rand.Rand, v Vector) {
+		vecs = append(vecs, v)
+	})
+
+	m, err := FromReader(bytes.NewReader(bin), RawVectors())
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	text := &bytes.Buffer{}
+	if err := m.WriteText(text); err != nil {
+		t.Fatalf("unexpected error from WriteText: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		bytes int64 // bytes read: the newline after the last binary vector is not read
+	}{
+		{"binary", bin, int64(len(bin) - 1)},
+		{"text", text.Bytes(), int64(text.Len())},
+	}
+
+	for _, tt := range tests {
+		var last Progress
+		calls := 0
+		m, err := LoadContext(context.Background(), bytes.NewReader(tt.data), ReportProgress(func(p Progress) {
+			if p.Entries <= last.Entries || p.Bytes <= last.Bytes {
+				t.Errorf("[%s] progress %+v after %+v, expected increase", tt.name, p, last)
+			}
+			last = p
+			calls++
+		}))
+		if err != nil {
+			t.Errorf("[%s] unexpected error from LoadContext: %v", tt.name, err)
+			continue
+		}
+		if m.Size() != n {
+			t.Errorf("[%s] m.Size() = %d, expected %d", tt.name, m.Size(), n)
+		}
+		for _, i := range []int{0, batchSize, n - 1} {
+			if w, _ := m.Word(i); w != testWord(i) {
+				t.Errorf("[%s] m.Word(%d) = %q, expected %q", tt.name, i, w, testWord(i))
+			}
+			if x := m.norms[i] * m.norms[i]; !approxEqual(x, vecs[i].Dot(vecs[i])) {
+				t.Errorf("[%s] norm^2 of %q = %v, expected %v", tt.name, testWord(i), x, vecs[i].Dot(vecs[i]))
+			}
+		}
+
+		if calls != 6 {
+			t.Errorf("[%s] progress called %d times, expected 6", tt.name, calls)
+		}
+		expected := Progress{Entries: n, Size: n, Bytes: tt.bytes, TotalBytes: int64(len(tt.data))}
+		if last != expected {
+			t.Errorf("[%s] last progress = %+v, expected %+v", tt.name, last, expected)
+		}
+	}
+}
+
+func TestLoadContextCancel(t *testing.T) {
+	data := testRandomModelData(t, 5*batchSize, 3, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := LoadContext(ctx, bytes.NewReader(data), ReportProgress(func(p Progress) {
+		cancel()
+	}))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadContext() error = %v, expected %v", err, context.Canceled)
+	}
+
+	m, err := LoadContext(context.Background(), bytes.NewReader(data), MaxSize(batchSize+1))
+	if err != nil {
+		t.Fatalf("unexpected error from LoadContext: %v", err)
+	}
+	if m.Size() != batchSize+1 {
+		t.Errorf("m.Size() = %d, expected %d", m.Size(), batchSize+1)
+	}
+}
@@ -0,0 +1,71 @@
+package word2vec
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FromTextReader creates a Model using the text model data provided by the io.Reader.
+// This is the format written by the word2vec tool with -binary 0, and is also used by
+// fastText (.vec) and GloVe (.txt).  Each line contains a word followed by the components
+// of its vector, separated by spaces.  The first line may optionally be a "size dim" header,
+// as GloVe files have no header, and lines after the number of entries it declares are an
+// error.  Errors in the model data are reported using ParseError.
+func FromTextReader(r io.Reader, opts ...Option) (*Model, error) {
+	return fromText(context.Background(), r, newOptions(opts))
+}
+
+func fromText(ctx context.Context, r io.Reader, o *options) (*Model, error) {
+	p := newParser(r, o)
+	max := p.o.limits.WordLength + 64*(p.o.limits.Dim+1)
+
+	line, err := p.line(max)
+	if err != nil {
+		return nil, p.error(err)
+	}
+
+	size, dim := -1, -1
+	fields := strings.Fields(line)
+	if s, d, ok := parseHeader(fields); ok {
+		size, dim = s, d
+		if err := p.checkHeader(size, dim); err != nil {
+			return nil, err
+		}
+		line = ""
+	} else {
+		// No header (GloVe): the dimension is implied by the first entry.
+		dim = len(fields) - 1
+		if dim <= 0 || dim > p.o.limits.Dim {
+			return nil, p.errorf("could not determine vector dimension from text model data")
+		}
+	}
+
+	expected := size
+	if size < 0 {
+		expected = p.o.limits.Size
+	}
+	b := newBuilder(p.o, p.prealloc(expected, dim, 2*dim+2), dim)
+	read := p.readText(size, max, line)
+	if err := p.stream(ctx, b, size, read, decodeText(dim, p.o.limits.WordLength)); err != nil {
+		return nil, err
+	}
+	return b.model()
+}
+
+// parseHeader returns the size and dimension from the fields of a "size dim" header line.
+func parseHeader(fields []string) (size, dim int, ok bool) {
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	size, err := strconv.Atoi(fields[0])
+	if err != nil || size < 0 {
+		return 0, 0, false
+	}
+	dim, err = strconv.Atoi(fields[1])
+	if err != nil || dim <= 0 {
+		return 0, 0, false
+	}
+	return size, dim, true
+}
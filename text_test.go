@@ -0,0 +1,152 @@
+package word2vec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFromTextReader(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "header",
+			data: "2 2\nhello 0 1\nworld 1 0\n",
+		},
+		{
+			name: "header trailing space",
+			data: "2 2\nhello 0 1 \nworld 1 0 \n",
+		},
+		{
+			name: "header trailing blank lines",
+			data: "2 2\nhello 0 1\nworld 1 0\n\n \n",
+		},
+		{
+			name: "no header",
+			data: "hello 0 1\nworld 1 0",
+		},
+		{
+			name: "no header scaled",
+			data: "hello 0 2.5\r\nworld 3e-1 0\r\n",
+		},
+	}
+
+	expected := map[string]Vector{
+		"hello": Vector{0, 1},
+		"world": Vector{1, 0},
+	}
+
+	for _, tt := range tests {
+		m, err := FromTextReader(strings.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("[%s] unexpected error from FromTextReader: %v", tt.name, err)
+			continue
+		}
+		if m.Size() != 2 {
+			t.Errorf("[%s] m.Size() = %d, expected 2", tt.name, m.Size())
+		}
+		if m.Dim() != 2 {
+			t.Errorf("[%s] m.Dim() = %d, expected 2", tt.name, m.Dim())
+		}
+		vecs := m.Map([]string{"hello", "world"})
+		if !reflect.DeepEqual(vecs, expected) {
+			t.Errorf("[%s] m.Map() = %v, expected %v", tt.name, vecs, expected)
+		}
+	}
+}
+
+func TestFromTextReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"truncated", "3 2\nhello 0 1\nworld 1 0\n"},
+		{"extra entry", "1 2\nhello 0 1\nworld 1 0\n"},
+		{"short entry", "2 2\nhello 0 1\nworld 1\n"},
+		{"bad value", "2 2\nhello 0 1\nworld 1 x\n"},
+	}
+
+	for _, tt := range tests {
+		if _, err := FromTextReader(strings.NewReader(tt.data)); err == nil {
+			t.Errorf("[%s] expected error from FromTextReader", tt.name)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	bin := &bytes.Buffer{}
+	fmt.Fprintln(bin, 2, 2)
+	for _, w := range []string{"hello", "world"} {
+		fmt.Fprintf(bin, "%s ", w)
+		v := Vector{0, 1}
+		if w == "world" {
+			v = Vector{1, 0}
+		}
+		if err := binary.Write(bin, binary.LittleEndian, v); err != nil {
+			t.Fatalf("unexpected error writing vector: %v", err)
+		}
+		fmt.Fprintf(bin, "\n")
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"binary", bin.Bytes()},
+		{"text", []byte("2 2\nhello 0 1\nworld 1 0\n")},
+		{"glove", []byte("hello 0 1\nworld 1 0\n")},
+	}
+
+	expected := map[string]Vector{
+		"hello": Vector{0, 1},
+		"world": Vector{1, 0},
+	}
+
+	for _, tt := range tests {
+		m, err := Load(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("[%s] unexpected error from Load: %v", tt.name, err)
+			continue
+		}
+		vecs := m.Map([]string{"hello", "world"})
+		if !reflect.DeepEqual(vecs, expected) {
+			t.Errorf("[%s] m.Map() = %v, expected %v", tt.name, vecs, expected)
+		}
+	}
+}
+
+func TestLoadLargeDim(t *testing.T) {
+	m, err := FromReader(bytes.NewReader(testRandomModelData(t, 3, 6000, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := m.WriteText(buf); err != nil {
+		t.Fatalf("unexpected error from WriteText: %v", err)
+	}
+	data := buf.Bytes()
+	if n := bytes.Index(data[bytes.IndexByte(data, '\n')+1:], []byte("\n")); n < sniffSize {
+		t.Fatalf("first entry is %d bytes, expected more than %d", n, sniffSize)
+	}
+
+	m2, err := Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from Load: %v", err)
+	}
+	if m2.Size() != 3 || m2.Dim() != 6000 {
+		t.Fatalf("Load() returned model of size %d, dim %d, expected size 3, dim 6000", m2.Size(), m2.Dim())
+	}
+	for i := 0; i < 3; i++ {
+		w := testWord(i)
+		v, v2 := m.Map([]string{w})[w], m2.Map([]string{w})[w]
+		if !approxEqual(v.Dot(v2), 1) {
+			t.Errorf("vector of %q after Load has cosine similarity %v with original, expected 1", w, v.Dot(v2))
+		}
+	}
+}
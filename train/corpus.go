@@ -0,0 +1,164 @@
+package train
+
+import (
+	"bufio"
+	"io"
+)
+
+// Corpus is an interface which defines a tokenised text corpus: words are separated by
+// whitespace, and each line is a sentence.  The corpus is read concurrently (each worker
+// reads its own section) and more than once (once for each iteration), so it must
+// support ReadAt.  *os.File can be used by wrapping it with io.NewSectionReader, and
+// *bytes.Reader and *strings.Reader implement Corpus directly.
+type Corpus interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// maxWordLength is the maximum length of a word in bytes, as in the original word2vec
+// tool.  Longer words are truncated.
+const maxWordLength = 100
+
+// maxSentenceLength is the maximum number of words in a sentence.  Longer lines are
+// split into several sentences.
+const maxSentenceLength = 1000
+
+// isSpace reports whether b separates words.
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+// scanner reads the words of a section of a corpus.  A word belongs to the section in
+// which it starts, so consecutive sections read each word of the corpus exactly once.
+// Scanners which read lines (see newLineScanner) instead read the whole of each line
+// which starts in the section.
+type scanner struct {
+	r        *bufio.Reader
+	pos, end int64
+	buf      []byte
+
+	lines  bool // read whole lines
+	inLine bool // part of the current line has been read
+}
+
+// newScanner returns a scanner which reads the words of section i of n equal sections of
+// the corpus c.
+func newScanner(c Corpus, i, n int) (*scanner, error) {
+	return openScanner(c, i, n, false)
+}
+
+// newLineScanner returns a scanner which reads the lines of section i of n equal
+// sections of the corpus c.
+func newLineScanner(c Corpus, i, n int) (*scanner, error) {
+	return openScanner(c, i, n, true)
+}
+
+func openScanner(c Corpus, i, n int, lines bool) (*scanner, error) {
+	lo, hi := c.Size()*int64(i)/int64(n), c.Size()*int64(i+1)/int64(n)
+	s := &scanner{
+		r:     bufio.NewReaderSize(io.NewSectionReader(c, lo, c.Size()-lo), 1<<16),
+		pos:   lo,
+		end:   hi,
+		buf:   make([]byte, 0, maxWordLength),
+		lines: lines,
+	}
+	if lo == 0 {
+		return s, nil
+	}
+
+	// Skip the rest of any word (or line) which started in the previous section.
+	b := []byte{0}
+	if _, err := c.ReadAt(b, lo-1); err != nil {
+		return nil, err
+	}
+	var err error
+	switch {
+	case lines && b[0] != '\n':
+		err = s.skipLine()
+	case !lines && !isSpace(b[0]):
+		err = s.skipWord()
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return s, nil
+}
+
+// skipLine skips bytes up to and including the next newline.
+func (s *scanner) skipLine() error {
+	for {
+		b, err := s.readByte()
+		if err != nil || b == '\n' {
+			return err
+		}
+	}
+}
+
+// skipWord skips bytes up to the next separator.
+func (s *scanner) skipWord() error {
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if isSpace(b) {
+			return s.r.UnreadByte()
+		}
+		s.pos++
+	}
+}
+
+func (s *scanner) readByte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err == nil {
+		s.pos++
+	}
+	return b, err
+}
+
+// next returns the next word in the section, or eol = true at the end of a line.  The
+// word is only valid until the next call.  Returns io.EOF at the end of the section.
+func (s *scanner) next() (word []byte, eol bool, err error) {
+	for {
+		if s.pos >= s.end && !(s.lines && s.inLine) {
+			return nil, false, io.EOF
+		}
+		b, err := s.readByte()
+		if err != nil {
+			return nil, false, err
+		}
+		if b == '\n' {
+			s.inLine = false
+			return nil, true, nil
+		}
+		s.inLine = true
+		if !isSpace(b) {
+			s.buf = append(s.buf[:0], b)
+			break
+		}
+	}
+
+	for {
+		b, err := s.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if isSpace(b) {
+			// Leave the separator to be read next, so newlines end the sentence.
+			s.r.UnreadByte()
+			break
+		}
+		s.pos++
+		if len(s.buf) < maxWordLength {
+			s.buf = append(s.buf, b)
+		}
+	}
+	return s.buf, false, nil
+}
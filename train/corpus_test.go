@@ -0,0 +1,49 @@
+package train
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	corpus := "the quick  brown\nfox\t jumps\n\n   over the " + strings.Repeat("x", maxWordLength+10) + "\nlazy dog"
+	expected := []string{"the", "quick", "brown", "\n", "fox", "jumps", "\n", "\n", "over", "the", strings.Repeat("x", maxWordLength), "\n", "lazy", "dog"}
+
+	c := strings.NewReader(corpus)
+	for _, lines := range []bool{false, true} {
+		for n := 1; n <= len(corpus); n++ {
+			var words []string
+			for i := 0; i < n; i++ {
+				sc, err := openScanner(c, i, n, lines)
+				if err != nil {
+					t.Fatalf("[%d] unexpected error from openScanner: %v", n, err)
+				}
+				var section []string
+				for {
+					w, eol, err := sc.next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatalf("[%d] unexpected error from next: %v", n, err)
+					}
+					if eol {
+						section = append(section, "\n")
+						continue
+					}
+					section = append(section, string(w))
+				}
+				// Line scanners read whole lines.
+				if lines && len(section) > 0 && section[len(section)-1] != "\n" && section[len(section)-1] != "dog" {
+					t.Errorf("[lines, %d sections] section %d = %q, expected whole lines", n, i, section)
+				}
+				words = append(words, section...)
+			}
+			if !reflect.DeepEqual(words, expected) {
+				t.Errorf("[lines %v, %d sections] words = %q, expected %q", lines, n, words, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,534 @@
+package train
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync/atomic"
+
+	"code.sajari.com/word2vec"
+)
+
+// DocMode is a type which represents the model used to train document vectors.
+type DocMode int
+
+// Document models.
+const (
+	// PVDM (distributed memory) predicts each word of a document from the mean of the
+	// document vector and the vectors of the words in the window around it.
+	PVDM DocMode = iota
+
+	// PVDBOW (distributed bag-of-words) predicts each word of a document from the
+	// document vector alone.
+	PVDBOW
+)
+
+func (m DocMode) String() string {
+	switch m {
+	case PVDM:
+		return "pv-dm"
+	case PVDBOW:
+		return "pv-dbow"
+	}
+	return fmt.Sprintf("DocMode(%d)", int(m))
+}
+
+// DocConfig is a type which configures the training of document vectors (see
+// DocTrainer).  Zero fields take their default values.
+type DocConfig struct {
+	// Config configures the dimension, window, vocabulary, negative sampling, learning
+	// rate, iterations, workers and seed.  Document vectors are always trained using
+	// negative sampling, so Arch, HierarchicalSoftmax and Freeze are ignored.
+	Config
+
+	Mode DocMode
+
+	// TrainWords also trains the word vectors using skip-gram in PVDBOW mode, which
+	// usually improves the document vectors.
+	TrainWords bool
+
+	// TiedWeights uses the word vectors as the output weights of negative sampling,
+	// rather than training separate output weights as standard PV-DM and PV-DBOW do.
+	// The vectors of new documents can then be inferred from the word vectors alone
+	// (see NewInferrer), but the trained vectors are usually less accurate.
+	TiedWeights bool
+
+	// InferIterations is the number of passes over a document when inferring its vector
+	// (see Inferrer).  Defaults to 20.
+	InferIterations int
+}
+
+func (c DocConfig) withDefaults() DocConfig {
+	c.Arch = SkipGram
+	c.HierarchicalSoftmax = false
+	c.Freeze = false
+	c.Config = c.Config.withDefaults()
+	if c.InferIterations <= 0 {
+		c.InferIterations = 20
+	}
+	return c
+}
+
+// DocTrainer is a type which trains vectors for the documents of a corpus (doc2vec),
+// together with word vectors.  Each line of the corpus is a document, and its first word
+// is the tag which identifies it (lines with the same tag are the same document).
+//
+// Document vectors are trained together with the word vectors, so they are in the same
+// space and can be compared using Vector.Dot.  The output weights of negative sampling
+// are trained separately (unless DocConfig.TiedWeights is set), and are needed as well as
+// the word vectors to infer vectors for new documents (see WriteWeights and Inferrer).
+// The embedded Trainer gives the word vectors (see Trainer.WriteBinary and
+// Trainer.Model).
+type DocTrainer struct {
+	*Trainer
+	dc DocConfig
+
+	tags  []string
+	index map[string]int32
+	docs  []float32 // document vectors
+}
+
+// NewDocTrainer counts the words and documents in the corpus c, and returns a DocTrainer
+// for them with randomly initialised vectors.
+func NewDocTrainer(ctx context.Context, c Corpus, config DocConfig) (*DocTrainer, error) {
+	config = config.withDefaults()
+	counts, tags, err := countDocs(ctx, c, config.Workers)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &DocTrainer{
+		Trainer: newTrainer(newVocab(counts, config.MinCount), config.Config, config.TiedWeights),
+		dc:      config,
+		tags:    tags,
+		index:   make(map[string]int32, len(tags)),
+		docs:    make([]float32, len(tags)*config.Dim),
+	}
+	for i, tag := range tags {
+		t.index[tag] = int32(i)
+	}
+	r := uint64(config.Seed) + 2
+	for i := range t.docs {
+		r = nextRandom(r)
+		t.docs[i] = (float32(r&0xFFFF)/65536 - 0.5) / float32(config.Dim)
+	}
+	return t, nil
+}
+
+// countDocs counts the words of the documents in c, and returns their tags in the order
+// they first occur.
+func countDocs(ctx context.Context, c Corpus, workers int) (map[string]int64, []string, error) {
+	workers = numWorkers(workers)
+	counts := make([]map[string]int64, workers)
+	tags := make([][]string, workers)
+	err := run(workers, func(i int) error {
+		sc, err := newLineScanner(c, i, workers)
+		if err != nil {
+			return err
+		}
+		counts[i] = make(map[string]int64)
+		first := true
+		for k := 0; ; k++ {
+			if k%checkInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			w, eol, err := sc.next()
+			switch {
+			case err == io.EOF:
+				return nil
+			case err != nil:
+				return err
+			case eol:
+				first = true
+			case first:
+				tags[i] = append(tags[i], string(w))
+				first = false
+			default:
+				counts[i][string(w)]++
+			}
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool)
+	var out []string
+	for i, m := range counts {
+		if i > 0 {
+			for w, n := range m {
+				counts[0][w] += n
+			}
+		}
+		for _, tag := range tags[i] {
+			if !seen[tag] {
+				seen[tag] = true
+				out = append(out, tag)
+			}
+		}
+	}
+	return counts[0], out, nil
+}
+
+// Docs returns the number of documents.
+func (t *DocTrainer) Docs() int {
+	return len(t.tags)
+}
+
+// Tag returns the tag of the document with index i.
+func (t *DocTrainer) Tag(i int) string {
+	return t.tags[i]
+}
+
+// DocVector returns the vector of the document with index i, which is shared with the
+// trainer.
+func (t *DocTrainer) DocVector(i int) word2vec.Vector {
+	return t.vector(t.docs, i)
+}
+
+// Train trains the document and word vectors for Config.Iterations passes over the
+// corpus c, which should be the corpus the trainer was created from.  Training stops
+// early if ctx is done, in which case ctx.Err() is returned.
+func (t *DocTrainer) Train(ctx context.Context, c Corpus) error {
+	atomic.StoreInt64(&t.words, 0)
+	return run(t.c.Workers, func(i int) error {
+		return t.work(ctx, c, i)
+	})
+}
+
+// work trains on the documents in section i of the corpus for each iteration.
+func (t *DocTrainer) work(ctx context.Context, c Corpus, i int) error {
+	w := t.newWorker(i)
+	var sen []int32
+	for it := 0; it < t.c.Iterations; it++ {
+		sc, err := newLineScanner(c, i, t.c.Workers)
+		if err != nil {
+			return err
+		}
+
+		for {
+			var doc int32
+			doc, sen, err = t.document(w, sc, sen[:0])
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if err := w.update(ctx, it); err != nil {
+				return err
+			}
+
+			if doc >= 0 {
+				d := t.DocVector(int(doc))
+				for pos := range sen {
+					if t.dc.Mode == PVDBOW {
+						t.dbow(w, d, sen, pos)
+					} else {
+						t.dm(w, d, sen, pos)
+					}
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+	}
+	atomic.AddInt64(&t.words, w.count)
+	return ctx.Err()
+}
+
+// document reads the next line from sc, and returns the index of its document (or -1 if
+// the line is empty or the document is unknown) and its words, skipping words which are
+// not in the vocabulary and down-sampling frequent words.
+func (t *DocTrainer) document(w *worker, sc *scanner, sen []int32) (int32, []int32, error) {
+	doc := int32(-1)
+	first := true
+	for {
+		word, eol, err := sc.next()
+		if err != nil || eol {
+			return doc, sen, err
+		}
+		if first {
+			first = false
+			if i, ok := t.index[string(word)]; ok {
+				doc = i
+			}
+			continue
+		}
+		if i, ok := t.vocab.index[string(word)]; ok && w.keep(i) {
+			sen = append(sen, i)
+		}
+	}
+}
+
+// dm trains the document vector d and the vectors of the words in the window around
+// position pos of sen to predict the word at pos.
+func (t *DocTrainer) dm(w *worker, d word2vec.Vector, sen []int32, pos int) {
+	lo, hi := w.window(sen, pos)
+	copy(w.neu1, d)
+	for i := range w.neu1e {
+		w.neu1e[i] = 0
+	}
+	for c := lo; c < hi; c++ {
+		if c != pos {
+			w.neu1.Add(1, t.Vector(int(sen[c])))
+		}
+	}
+	n := float32(hi - lo)
+	for i := range w.neu1 {
+		w.neu1[i] /= n
+	}
+
+	w.predict(w.neu1, sen[pos])
+	d.Add(1, w.neu1e)
+	for c := lo; c < hi; c++ {
+		if c != pos {
+			t.Vector(int(sen[c])).Add(1, w.neu1e)
+		}
+	}
+}
+
+// dbow trains the document vector d to predict the word at position pos of sen, and
+// trains the word vectors using skip-gram if DocConfig.TrainWords is set.
+func (t *DocTrainer) dbow(w *worker, d word2vec.Vector, sen []int32, pos int) {
+	for i := range w.neu1e {
+		w.neu1e[i] = 0
+	}
+	w.predict(d, sen[pos])
+	d.Add(1, w.neu1e)
+	if t.dc.TrainWords {
+		w.skipGram(sen, pos)
+	}
+}
+
+// WriteDocs writes the trained document vectors to w in the binary word2vec format, with
+// the document tags as words.
+func (t *DocTrainer) WriteDocs(w io.Writer) error {
+	return writeBinary(w, t.tags, t.c.Dim, t.DocVector)
+}
+
+// DocModel returns a *word2vec.Model of the trained document vectors, with the document
+// tags as words, loaded with the given options.
+func (t *DocTrainer) DocModel(opts ...word2vec.Option) (*word2vec.Model, error) {
+	return loadModel(t.WriteDocs, opts)
+}
+
+// WriteWeights writes the output weights of negative sampling to w in the binary word2vec
+// format, with the words of the vocabulary, for inferring document vectors (see
+// NewInferrer).  With DocConfig.TiedWeights they are the word vectors.
+func (t *DocTrainer) WriteWeights(w io.Writer) error {
+	return writeBinary(w, t.vocab.words, t.c.Dim, func(i int) word2vec.Vector {
+		return t.vector(t.syn1neg, i)
+	})
+}
+
+// WeightsModel returns a *word2vec.Model of the output weights of negative sampling (see
+// WriteWeights), loaded with the given options.
+func (t *DocTrainer) WeightsModel(opts ...word2vec.Option) (*word2vec.Model, error) {
+	return loadModel(t.WriteWeights, opts)
+}
+
+// TrainDocs trains document and word vectors on the corpus c (see DocTrainer) configured
+// by config, and returns models of the word and document vectors.
+func TrainDocs(ctx context.Context, c Corpus, config DocConfig) (words, docs *word2vec.Model, err error) {
+	t, err := NewDocTrainer(ctx, c, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := t.Train(ctx, c); err != nil {
+		return nil, nil, err
+	}
+	if words, err = t.Model(word2vec.RawVectors()); err != nil {
+		return nil, nil, err
+	}
+	if docs, err = t.DocModel(word2vec.RawVectors()); err != nil {
+		return nil, nil, err
+	}
+	return words, docs, nil
+}
+
+// Inferrer is a type which infers vectors for new documents by training them against the
+// frozen word vectors and output weights of a trained DocTrainer.
+type Inferrer struct {
+	m, weights *word2vec.Model // word vectors and output weights (which may be the same)
+	c          DocConfig
+
+	// Factors which give the vectors of m and weights in the model data.
+	scales, weightScales []float32
+
+	cum  []float64 // cumulative distribution of negative samples, by word ID
+	keep []float64 // probability of keeping each word when down-sampling, nil if none
+}
+
+// NewInferrer returns an Inferrer which infers document vectors using the word vectors m
+// and output weights of a DocTrainer with the same DocConfig.Mode (see
+// DocTrainer.WriteWeights).  If the trainer used DocConfig.TiedWeights, weights should be
+// nil, and the word vectors are used as the output weights.  Vectors are used as they
+// appeared in the model data, whether or not the models were loaded with RawVectors.
+// Model data does not contain word counts, so negative samples are drawn assuming the
+// words of m are sorted by descending frequency with frequencies following Zipf's law, as
+// for models written by a DocTrainer, and frequent words are not down-sampled.  When the
+// trainer is still available, DocTrainer.Inferrer uses the actual counts, which gives
+// vectors closer to the trained ones (particularly in PVDM mode).  Returns an error if
+// weights does not have the same words and dimension as m.
+func NewInferrer(m, weights *word2vec.Model, c DocConfig) (*Inferrer, error) {
+	if weights == nil {
+		weights = m
+	}
+	if weights.Size() != m.Size() || weights.Dim() != m.Dim() {
+		return nil, fmt.Errorf("weights have %d words of dimension %d, expected %d words of dimension %d", weights.Size(), weights.Dim(), m.Size(), m.Dim())
+	}
+	for i := 0; i < m.Size(); i++ {
+		w, _ := m.Word(i)
+		if u, _ := weights.Word(i); u != w {
+			return nil, fmt.Errorf("weights have word %q with ID %d, expected %q", u, i, w)
+		}
+	}
+	return newInferrer(m, weights, c, func(i int) float64 { return 1 / float64(i+1) }), nil
+}
+
+// Inferrer returns an Inferrer which infers document vectors using the trained word
+// vectors and output weights, drawing negative samples and down-sampling frequent words
+// using the word counts of the vocabulary, as in training (see NewInferrer).  The
+// Inferrer uses a copy of the vectors, so is not affected by further training.
+func (t *DocTrainer) Inferrer() (*Inferrer, error) {
+	m, err := t.Model(word2vec.RawVectors())
+	if err != nil {
+		return nil, err
+	}
+	weights := m
+	if !t.dc.TiedWeights {
+		if weights, err = t.WeightsModel(word2vec.RawVectors()); err != nil {
+			return nil, err
+		}
+	}
+	in := newInferrer(m, weights, t.dc, func(i int) float64 { return float64(t.vocab.counts[i]) })
+	if t.c.Sample > 0 {
+		in.keep = make([]float64, len(t.vocab.counts))
+		for i, n := range t.vocab.counts {
+			in.keep[i] = keepProbability(n, t.vocab.total, t.c.Sample)
+		}
+	}
+	return in, nil
+}
+
+// newInferrer returns an Inferrer which draws negative samples with probability
+// proportional to count(i)^0.75 for the word with ID i.
+func newInferrer(m, weights *word2vec.Model, c DocConfig, count func(i int) float64) *Inferrer {
+	in := &Inferrer{
+		m:       m,
+		weights: weights,
+		c:       c.withDefaults(),
+		scales:  make([]float32, m.Size()),
+		cum:     make([]float64, m.Size()),
+	}
+	var total float64
+	m.Range(func(i int, w string, v word2vec.Vector) bool {
+		in.scales[i] = rawScale(m, w, v)
+		total += math.Pow(count(i), 0.75)
+		in.cum[i] = total
+		return true
+	})
+	in.weightScales = in.scales
+	if weights != m {
+		in.weightScales = make([]float32, weights.Size())
+		weights.Range(func(i int, w string, v word2vec.Vector) bool {
+			in.weightScales[i] = rawScale(weights, w, v)
+			return true
+		})
+	}
+	return in
+}
+
+// Infer returns the vector of the document made of words, trained for
+// DocConfig.InferIterations passes over the words with the word vectors fixed.  Words
+// which are not in the model are ignored (if there are none, the vector keeps its random
+// initialisation).  For a given DocConfig.Seed the result depends only on the words.
+// Infer can be called concurrently.
+func (in *Inferrer) Infer(words []string) word2vec.Vector {
+	dim := in.m.Dim()
+	r := uint64(in.c.Seed) + 2
+	d := make(word2vec.Vector, dim)
+	for i := range d {
+		r = nextRandom(r)
+		d[i] = (float32(r&0xFFFF)/65536 - 0.5) / float32(dim)
+	}
+
+	ids := make([]int32, 0, len(words))
+	for _, w := range words {
+		if i, err := in.m.ID(w); err == nil {
+			ids = append(ids, int32(i))
+		}
+	}
+
+	neu1 := make(word2vec.Vector, dim)
+	neu1e := make(word2vec.Vector, dim)
+	sen := make([]int32, 0, len(ids))
+	total := float64(in.c.InferIterations*len(ids) + 1)
+	for it := 0; it < in.c.InferIterations; it++ {
+		alpha := float32(in.c.Alpha * (1 - float64(it*len(ids))/total))
+		if min := float32(in.c.Alpha * 1e-4); alpha < min {
+			alpha = min
+		}
+
+		// Down-sample frequent words, as in training.
+		sen = sen[:0]
+		for _, i := range ids {
+			r = nextRandom(r)
+			if in.keep == nil || in.keep[i] >= float64(r&0xFFFF)/65536 {
+				sen = append(sen, i)
+			}
+		}
+
+		for pos := range sen {
+			for i := range neu1e {
+				neu1e[i] = 0
+			}
+			l1 := d
+			if in.c.Mode == PVDM {
+				lo, hi := shrinkWindow(&r, in.c.Window, pos, len(sen))
+				copy(neu1, d)
+				for c := lo; c < hi; c++ {
+					if c != pos {
+						neu1.Add(in.scales[sen[c]], vectorByID(in.m, sen[c]))
+					}
+				}
+				n := float32(hi - lo)
+				for i := range neu1 {
+					neu1[i] /= n
+				}
+				l1 = neu1
+			}
+			in.predict(l1, sen[pos], alpha, &r, neu1e)
+			d.Add(1, neu1e)
+		}
+	}
+	return d
+}
+
+// vectorByID returns the vector of the word with ID i in m.
+func vectorByID(m *word2vec.Model, i int32) word2vec.Vector {
+	v, _ := m.VectorByID(int(i))
+	return v
+}
+
+// predict adds the gradient of the prediction of word from l1 to neu1e, using the (fixed)
+// output weights.
+func (in *Inferrer) predict(l1 word2vec.Vector, word int32, alpha float32, r *uint64, neu1e word2vec.Vector) {
+	for d := 0; d <= in.c.Negative; d++ {
+		target, label := word, float32(1)
+		if d > 0 {
+			*r = nextRandom(*r)
+			u := float64(*r>>16&0xFFFFFFFF) / (1 << 32) * in.cum[len(in.cum)-1]
+			target, label = int32(sort.SearchFloat64s(in.cum, u)), 0
+			if target == word || int(target) >= len(in.cum) {
+				continue
+			}
+		}
+		l2, s := vectorByID(in.weights, target), in.weightScales[target]
+		g := gradient(s*l1.Dot(l2), label, alpha)
+		neu1e.Add(g*s, l2)
+	}
+}
@@ -0,0 +1,154 @@
+package train
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.sajari.com/word2vec"
+)
+
+// testDocCorpus returns a corpus of documents d0...d(n-1), each made of words from topic
+// a (for even documents) or b (for odd documents), as in testCorpus.
+func testDocCorpus(n int) *strings.Reader {
+	r := rand.New(rand.NewSource(1))
+	b := &strings.Builder{}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(b, "d%d", i)
+		for j := 0; j < 20; j++ {
+			fmt.Fprintf(b, " %c%d", "ab"[i%2], r.Intn(10))
+		}
+		b.WriteByte('\n')
+	}
+	return strings.NewReader(b.String())
+}
+
+func cos(x, y word2vec.Vector) float32 {
+	return x.Dot(y) / x.Norm() / y.Norm()
+}
+
+func TestTrainDocs(t *testing.T) {
+	c := testDocCorpus(200)
+	tests := []struct {
+		mode DocMode
+		tied bool
+	}{{PVDM, false}, {PVDBOW, false}, {PVDM, true}, {PVDBOW, true}}
+	for _, tt := range tests {
+		test := tt.mode.String()
+		if tt.tied {
+			test += ", tied"
+		}
+		config := DocConfig{
+			Config:      Config{Dim: 20, MinCount: 1, Iterations: 10, Workers: 1, Seed: 1},
+			Mode:        tt.mode,
+			TrainWords:  true,
+			TiedWeights: tt.tied,
+		}
+		tr, err := NewDocTrainer(context.Background(), c, config)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error from NewDocTrainer: %v", test, err)
+		}
+		if tr.Docs() != 200 || tr.Tag(3) != "d3" || tr.Vocab().Size() != 20 {
+			t.Errorf("[%s] %d docs (doc 3 %q), %d words, expected 200 docs (doc 3 \"d3\"), 20 words", test, tr.Docs(), tr.Tag(3), tr.Vocab().Size())
+		}
+		if err := tr.Train(context.Background(), c); err != nil {
+			t.Fatalf("[%s] unexpected error from Train: %v", test, err)
+		}
+
+		// Documents are closer to documents on the same topic, once the mean document
+		// vector (which is large with untied weights, as for word vectors) is removed.
+		mean := make(word2vec.Vector, 20)
+		for i := 0; i < 200; i++ {
+			mean.Add(1.0/200, tr.DocVector(i))
+		}
+		centred := func(i int) word2vec.Vector {
+			v := append(word2vec.Vector(nil), tr.DocVector(i)...)
+			v.Add(-1, mean)
+			return v
+		}
+		var same, different float32
+		for i := 2; i < 200; i++ {
+			s := cos(centred(0), centred(i))
+			if i%2 == 0 {
+				same += s
+			} else {
+				different += s
+			}
+		}
+		if same < different+0.3*99 {
+			t.Errorf("[%s] mean similarity of documents on the same topic %v, different topics %v, expected same > different + 0.3", test, same/98, different/99)
+		}
+
+		words, err := tr.Model(word2vec.RawVectors())
+		if err != nil {
+			t.Fatalf("[%s] unexpected error from Model: %v", test, err)
+		}
+		docs, err := tr.DocModel()
+		if err != nil {
+			t.Fatalf("[%s] unexpected error from DocModel: %v", test, err)
+		}
+		if docs.Size() != 200 || docs.Dim() != 20 {
+			t.Errorf("[%s] doc model size %d, dim %d, expected size 200, dim 20", test, docs.Size(), docs.Dim())
+		}
+
+		// Inferred vectors are close to documents and words on the same topic.  The words of
+		// the corpus have equal frequencies, so NewInferrer's assumption of Zipf's law only
+		// holds up in PVDBOW mode.
+		var weights *word2vec.Model
+		if !tt.tied {
+			if weights, err = tr.WeightsModel(); err != nil {
+				t.Fatalf("[%s] unexpected error from WeightsModel: %v", test, err)
+			}
+		}
+		inferrers := make(map[string]*Inferrer)
+		if inferrers["NewInferrer"], err = NewInferrer(words, weights, config); err != nil {
+			t.Fatalf("[%s] unexpected error from NewInferrer: %v", test, err)
+		}
+		if inferrers["Inferrer"], err = tr.Inferrer(); err != nil {
+			t.Fatalf("[%s] unexpected error from Inferrer: %v", test, err)
+		}
+		if _, err := NewInferrer(words, docs, config); err == nil {
+			t.Errorf("[%s] NewInferrer() with document vectors as weights returned nil error", test)
+		}
+		for name, in := range inferrers {
+			for _, topic := range []string{"a", "b"} {
+				var doc []string
+				for i := 0; i < 20; i++ {
+					doc = append(doc, fmt.Sprintf("%s%d", topic, i%10))
+				}
+				v := in.Infer(doc)
+				if u := in.Infer(doc); !reflect.DeepEqual(u, v) {
+					t.Errorf("[%s, %s] Infer() = %v, then %v, expected the same vector", test, name, v, u)
+				}
+				if name == "NewInferrer" && tt.mode == PVDM {
+					continue
+				}
+
+				// Mean similarity to documents and words on the same topic, and on the other topic.
+				var sim [2][2]float32
+				docs.Range(func(i int, _ string, u word2vec.Vector) bool {
+					if "ab"[i%2] == topic[0] {
+						sim[0][0] += cos(v, u) / 100
+					} else {
+						sim[0][1] += cos(v, u) / 100
+					}
+					return true
+				})
+				words.Range(func(_ int, w string, u word2vec.Vector) bool {
+					if w[0] == topic[0] {
+						sim[1][0] += cos(v, u) / 10
+					} else {
+						sim[1][1] += cos(v, u) / 10
+					}
+					return true
+				})
+				if sim[0][0] < sim[0][1]+0.3 || sim[1][0] < sim[1][1]+0.3 {
+					t.Errorf("[%s, %s] inferred %v document has mean similarity %v to documents and %v to words on the same topic, %v and %v on the other, expected same > other + 0.3", test, name, topic, sim[0][0], sim[1][0], sim[0][1], sim[1][1])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package train
+
+import (
+	"context"
+	"fmt"
+
+	"code.sajari.com/word2vec"
+)
+
+// FromModel returns a Trainer which continues training the vectors of m on the corpus c
+// (see Trainer.Train), so that the new vectors stay in the same space as those of m.  The
+// vocabulary starts with the words of m, in the same order, followed by the words of c
+// which are not in m and occur at least Config.MinCount times.  New words have randomly
+// initialised vectors, and with Config.Freeze the vectors of the words of m are not
+// updated.
+//
+// Vectors are trained from the vectors of m as they appeared in the model data (see
+// word2vec.Model.Norm), whether or not m was loaded with RawVectors.  Model data does not
+// contain the output weights used in training, so these are trained from scratch: a
+// lower Config.Alpha than used to train m, or a first pass with Config.Freeze, avoids
+// large changes to the vectors of m while they are learned.  Config.Dim defaults to the
+// dimension of m, and it is an error for it to differ.
+func FromModel(ctx context.Context, m *word2vec.Model, c Corpus, config Config) (*Trainer, error) {
+	if config.Dim == 0 {
+		config.Dim = m.Dim()
+	}
+	if config.Dim != m.Dim() {
+		return nil, fmt.Errorf("dimension %d does not match model dimension %d", config.Dim, m.Dim())
+	}
+	config = config.withDefaults()
+
+	counts, err := countCorpus(ctx, c, config.Workers)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]string, 0, m.Size())
+	m.Range(func(_ int, w string, _ word2vec.Vector) bool {
+		words = append(words, w)
+		return true
+	})
+
+	t := New(extendVocab(words, counts, config.MinCount), config)
+	m.Range(func(i int, w string, v word2vec.Vector) bool {
+		scale := rawScale(m, w, v)
+		for j, x := range v {
+			t.syn0[i*config.Dim+j] = scale * x
+		}
+		return true
+	})
+	if config.Freeze {
+		t.frozen = m.Size()
+	}
+	return t, nil
+}
+
+// FineTune continues training the vectors of m on the corpus c (see FromModel), and
+// returns a model of the trained vectors.
+func FineTune(ctx context.Context, m *word2vec.Model, c Corpus, config Config) (*word2vec.Model, error) {
+	t, err := FromModel(ctx, m, c, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Train(ctx, c); err != nil {
+		return nil, err
+	}
+	return t.Model()
+}
+
+// rawScale returns the factor by which v, the vector of w in m, must be multiplied to give
+// the vector as it appeared in the model data.
+func rawScale(m *word2vec.Model, w string, v word2vec.Vector) float32 {
+	if n, err := m.Norm(w); err == nil && v.Norm() > 0 {
+		return n / v.Norm()
+	}
+	return 1
+}
@@ -0,0 +1,71 @@
+package train
+
+import (
+	"context"
+	"testing"
+
+	"code.sajari.com/word2vec"
+)
+
+func TestFineTune(t *testing.T) {
+	base, err := Train(context.Background(), testCorpus(2000, "a", "b"), Config{Dim: 20, MinCount: 1, Workers: 1, Seed: 1})
+	if err != nil {
+		t.Fatalf("unexpected error from Train: %v", err)
+	}
+
+	// The new corpus introduces words c0...c9, which occur with a0...a9.
+	c := testCorpus(2000, "ac", "b")
+	for _, freeze := range []bool{false, true} {
+		m, err := FineTune(context.Background(), base, c, Config{MinCount: 1, Workers: 1, Seed: 2, Freeze: freeze})
+		if err != nil {
+			t.Errorf("[freeze %v] unexpected error from FineTune: %v", freeze, err)
+			continue
+		}
+		if m.Size() != 31 || m.Dim() != 20 {
+			t.Errorf("[freeze %v] model size %d, dim %d, expected size 31, dim 20", freeze, m.Size(), m.Dim())
+		}
+
+		base.Range(func(id int, w string, v word2vec.Vector) bool {
+			if x, _ := m.Word(id); x != w {
+				t.Errorf("[freeze %v] m.Word(%d) = %q, expected %q", freeze, id, x, w)
+			}
+			u, _ := m.VectorByID(id)
+			if freeze && !approxEqualVectors(u, v) {
+				t.Errorf("[freeze %v] vector %q = %v, expected %v", freeze, w, u, v)
+			}
+			return true
+		})
+
+		if ac, bc := topicSimilarity(t, m, "a", "c"), topicSimilarity(t, m, "b", "c"); ac < bc+0.3 {
+			t.Errorf("[freeze %v] mean similarity of c to a %v, to b %v, expected to a > to b + 0.3", freeze, ac, bc)
+		}
+		if aa := topicSimilarity(t, m, "a", "a"); aa < 0.5 {
+			t.Errorf("[freeze %v] mean similarity within a %v, expected > 0.5", freeze, aa)
+		}
+	}
+
+	if _, err := FromModel(context.Background(), base, c, Config{Dim: 10}); err == nil {
+		t.Errorf("expected error from FromModel with different dimension")
+	}
+}
+
+func TestUnigramTable(t *testing.T) {
+	v := extendVocab([]string{"a", "b", "c"}, map[string]int64{"a": 0, "b": 81, "c": 0, "d": 16}, 1)
+	counts := make([]int, v.Size())
+	for _, i := range unigramTable(v) {
+		counts[i]++
+	}
+	// Words are sampled in proportion to their count to the power 3/4: 27:8.
+	if counts[0] != 0 || counts[2] != 0 || counts[1]*8 < counts[3]*27-1000 || counts[1]*8 > counts[3]*27+1000 {
+		t.Errorf("unigramTable() counts = %v, expected 0 for words with count 0 and 27:8 for the others", counts)
+	}
+}
+
+func approxEqualVectors(x, y word2vec.Vector) bool {
+	for i := range x {
+		if d := x[i] - y[i]; d < -1e-5 || d > 1e-5 {
+			return false
+		}
+	}
+	return len(x) == len(y)
+}
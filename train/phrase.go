@@ -0,0 +1,251 @@
+package train
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"code.sajari.com/word2vec"
+)
+
+// PhraseConfig is a type which configures phrase learning (see LearnPhrases).  Zero
+// fields take their default values, which are those of the original word2phrase tool.
+type PhraseConfig struct {
+	// MinCount is the minimum number of times a word or bigram must occur in the corpus
+	// to be part of a phrase.  Defaults to 5.
+	MinCount int
+
+	// Threshold is the minimum score of a bigram for it to be joined into a phrase.  The
+	// score of bigram "a b" is (count(a b) - MinCount) / (count(a) * count(b)) * total,
+	// where total is the number of words in the corpus.  Higher thresholds give fewer
+	// phrases.  Defaults to 100.
+	Threshold float64
+
+	// Passes is the number of passes over the corpus.  Each pass joins bigrams of the
+	// words and phrases of the previous pass, so phrases of up to 2^Passes words can be
+	// found.  Defaults to 1.
+	Passes int
+
+	// Separator joins the words of a phrase, and should also be passed to
+	// word2vec.AddPhrases.  Defaults to word2vec.PhraseSeparator.
+	Separator string
+
+	// Workers is the number of goroutines used to count bigrams.  Defaults to GOMAXPROCS.
+	Workers int
+}
+
+func (c PhraseConfig) withDefaults() PhraseConfig {
+	if c.MinCount <= 0 {
+		c.MinCount = 5
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = 100
+	}
+	if c.Passes <= 0 {
+		c.Passes = 1
+	}
+	if c.Separator == "" {
+		c.Separator = word2vec.PhraseSeparator
+	}
+	c.Workers = numWorkers(c.Workers)
+	return c
+}
+
+// bigram is a pair of consecutive words.
+type bigram [2]string
+
+// Phrases is a type which holds the phrases learned from a corpus (see LearnPhrases).
+type Phrases struct {
+	sep    string
+	passes []map[bigram]float64 // score of each bigram joined in each pass
+}
+
+// LearnPhrases scores the bigrams in the corpus c, and returns the Phrases of those which
+// score above the threshold.  Each pass reads the whole corpus.
+func LearnPhrases(ctx context.Context, c Corpus, config PhraseConfig) (*Phrases, error) {
+	config = config.withDefaults()
+	p := &Phrases{sep: config.Separator}
+	for i := 0; i < config.Passes; i++ {
+		pass, err := p.learn(ctx, c, config)
+		if err != nil {
+			return nil, err
+		}
+		p.passes = append(p.passes, pass)
+	}
+	return p, nil
+}
+
+// phraseCounts holds the counts of words and bigrams.
+type phraseCounts struct {
+	words   map[string]int64
+	bigrams map[bigram]int64
+	total   int64
+}
+
+// learn counts the words and bigrams in c (after joining the phrases of the passes so
+// far), and returns the bigrams which score above the threshold.
+func (p *Phrases) learn(ctx context.Context, c Corpus, config PhraseConfig) (map[bigram]float64, error) {
+	counts := make([]phraseCounts, config.Workers)
+	err := run(config.Workers, func(i int) (err error) {
+		counts[i], err = p.count(ctx, c, i, config.Workers)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, x := range counts[1:] {
+		for w, n := range x.words {
+			counts[0].words[w] += n
+		}
+		for b, n := range x.bigrams {
+			counts[0].bigrams[b] += n
+		}
+		counts[0].total += x.total
+	}
+
+	all := counts[0]
+	min := int64(config.MinCount)
+	pass := make(map[bigram]float64)
+	for b, n := range all.bigrams {
+		na, nb := all.words[b[0]], all.words[b[1]]
+		if n < min || na < min || nb < min {
+			continue
+		}
+		score := float64(n-min) / float64(na) / float64(nb) * float64(all.total)
+		if score > config.Threshold {
+			pass[b] = score
+		}
+	}
+	return pass, nil
+}
+
+// count counts the words and bigrams in section i of n sections of c.  Bigrams which
+// span two sections are not counted.
+func (p *Phrases) count(ctx context.Context, c Corpus, i, n int) (phraseCounts, error) {
+	counts := phraseCounts{
+		words:   make(map[string]int64),
+		bigrams: make(map[bigram]int64),
+	}
+	sc, err := newScanner(c, i, n)
+	if err != nil {
+		return counts, err
+	}
+
+	var sen []string
+	for k := 0; ; k++ {
+		if k%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return counts, err
+			}
+		}
+		sen, err = sc.sentence(sen[:0])
+		if err != nil && err != io.EOF {
+			return counts, err
+		}
+		sen = p.join(sen, len(p.passes))
+		for j, w := range sen {
+			counts.words[w]++
+			if j > 0 {
+				counts.bigrams[bigram{sen[j-1], w}]++
+			}
+		}
+		counts.total += int64(len(sen))
+		if err == io.EOF {
+			return counts, nil
+		}
+	}
+}
+
+// sentence reads the words of the next line from sc into sen.  Returns io.EOF at the end
+// of the section.
+func (s *scanner) sentence(sen []string) ([]string, error) {
+	for {
+		w, eol, err := s.next()
+		if err != nil || eol {
+			return sen, err
+		}
+		sen = append(sen, string(w))
+	}
+}
+
+// join joins the bigrams of words which are phrases in the first n passes, in place.  As
+// in the original word2phrase tool, each pass scans the words from left to right, and a
+// word which has been joined to the previous word is not joined to the next.
+func (p *Phrases) join(words []string, n int) []string {
+	for _, pass := range p.passes[:n] {
+		out := words[:0]
+		joined := false
+		for _, w := range words {
+			if len(out) > 0 && !joined {
+				if _, ok := pass[bigram{out[len(out)-1], w}]; ok {
+					out[len(out)-1] += p.sep + w
+					joined = true
+					continue
+				}
+			}
+			out = append(out, w)
+			joined = false
+		}
+		words = out
+	}
+	return words
+}
+
+// Join returns the words with consecutive words which form phrases joined by the
+// separator.  The words slice is modified.
+func (p *Phrases) Join(words []string) []string {
+	return p.join(words, len(p.passes))
+}
+
+// Len returns the number of phrases learned.
+func (p *Phrases) Len() int {
+	var n int
+	for _, pass := range p.passes {
+		n += len(pass)
+	}
+	return n
+}
+
+// Score returns the score of the phrase made by joining the words a and b (either of
+// which may itself be a phrase), and false if they do not form a phrase.
+func (p *Phrases) Score(a, b string) (float64, bool) {
+	for _, pass := range p.passes {
+		if s, ok := pass[bigram{a, b}]; ok {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+func isSpaceRune(r rune) bool {
+	return r < 0x80 && isSpace(byte(r))
+}
+
+// Rewrite reads text from r and writes it to w with the words of each line which form
+// phrases joined by the separator.  Words are written separated by single spaces.
+func (p *Phrases) Rewrite(w io.Writer, r io.Reader) error {
+	br := bufio.NewReaderSize(r, 1<<16)
+	bw := bufio.NewWriter(w)
+	var words []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(line) > 0 {
+			words = p.Join(append(words[:0], strings.FieldsFunc(line, isSpaceRune)...))
+			if _, werr := bw.WriteString(strings.Join(words, " ")); werr != nil {
+				return werr
+			}
+			if strings.HasSuffix(line, "\n") {
+				if werr := bw.WriteByte('\n'); werr != nil {
+					return werr
+				}
+			}
+		}
+		if err == io.EOF {
+			return bw.Flush()
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package train
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPhraseCorpus returns a corpus of random words w0...w99, in which the phrases
+// "new york city" and "los angeles" often occur.
+func testPhraseCorpus() *strings.Reader {
+	r := rand.New(rand.NewSource(1))
+	b := &strings.Builder{}
+	for i := 0; i < 2000; i++ {
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteByte(' ')
+			}
+			switch r.Intn(20) {
+			case 0:
+				b.WriteString("new york city")
+			case 1:
+				b.WriteString("los angeles")
+			default:
+				fmt.Fprintf(b, "w%d", r.Intn(100))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return strings.NewReader(b.String())
+}
+
+func TestLearnPhrases(t *testing.T) {
+	c := testPhraseCorpus()
+	tests := []struct {
+		passes  int
+		phrases []string
+		words   []string
+	}{
+		// "york city" is a phrase, but "york" is joined to "new" first.
+		{1, []string{"new york", "york city", "los angeles"}, []string{"w1", "new_york", "city", "los_angeles", "new", "w2"}},
+		{2, []string{"new york", "york city", "los angeles", "new_york city"}, []string{"w1", "new_york_city", "los_angeles", "new", "w2"}},
+	}
+
+	for _, tt := range tests {
+		p, err := LearnPhrases(context.Background(), c, PhraseConfig{Threshold: 10, Passes: tt.passes, Workers: 2})
+		if err != nil {
+			t.Errorf("[%d] unexpected error from LearnPhrases: %v", tt.passes, err)
+			continue
+		}
+		if p.Len() != len(tt.phrases) {
+			t.Errorf("[%d] p.Len() = %d, expected %d", tt.passes, p.Len(), len(tt.phrases))
+		}
+		for _, x := range tt.phrases {
+			f := strings.Fields(x)
+			if _, ok := p.Score(f[0], f[1]); !ok {
+				t.Errorf("[%d] p.Score(%q, %q) = false, expected true", tt.passes, f[0], f[1])
+			}
+		}
+
+		words := p.Join(strings.Fields("w1 new york city los angeles new w2"))
+		if !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("[%d] p.Join() = %q, expected %q", tt.passes, words, tt.words)
+		}
+	}
+
+	p, err := LearnPhrases(context.Background(), c, PhraseConfig{Threshold: 10, Passes: 2})
+	if err != nil {
+		t.Fatalf("unexpected error from LearnPhrases: %v", err)
+	}
+	out := &bytes.Buffer{}
+	if err := p.Rewrite(out, strings.NewReader("w1  new york city\n\nlos\tangeles w2")); err != nil {
+		t.Fatalf("unexpected error from Rewrite: %v", err)
+	}
+	if expected := "w1 new_york_city\n\nlos_angeles w2"; out.String() != expected {
+		t.Errorf("Rewrite() = %q, expected %q", out.String(), expected)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := LearnPhrases(ctx, c, PhraseConfig{}); err != context.Canceled {
+		t.Errorf("LearnPhrases() error = %v, expected %v", err, context.Canceled)
+	}
+}
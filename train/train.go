@@ -0,0 +1,621 @@
+/*
+Package train trains word2vec models from a tokenised text corpus using skip-gram or
+continuous bag-of-words (CBOW), with negative sampling or hierarchical softmax, as in the
+original word2vec tool.
+
+	f, err := os.Open("/path/to/corpus.txt")
+	if err != nil {
+		log.Fatalf("error opening corpus: %v", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		log.Fatalf("error opening corpus: %v", err)
+	}
+
+	m, err := train.Train(ctx, io.NewSectionReader(f, 0, fi.Size()), train.Config{Dim: 200})
+	if err != nil {
+		log.Fatalf("error training model: %v", err)
+	}
+
+Trained models can be written in the binary word2vec format (see Trainer.WriteBinary),
+or used directly as a *word2vec.Model.
+
+Document vectors (doc2vec) are trained by a DocTrainer, and vectors for new documents
+are inferred by an Inferrer.  As in standard PV-DM and PV-DBOW, the output weights are
+trained separately from the word vectors unless DocConfig.TiedWeights is set.
+*/
+package train
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"code.sajari.com/word2vec"
+)
+
+// Arch is a type which represents the model architecture.
+type Arch int
+
+// Model architectures.
+const (
+	// SkipGram predicts the words in the window around each word from the word.
+	SkipGram Arch = iota
+
+	// CBOW (continuous bag-of-words) predicts each word from the mean of the words in the
+	// window around it.
+	CBOW
+)
+
+func (a Arch) String() string {
+	switch a {
+	case SkipGram:
+		return "skip-gram"
+	case CBOW:
+		return "cbow"
+	}
+	return fmt.Sprintf("Arch(%d)", int(a))
+}
+
+// Config is a type which configures training.  Zero fields take their default values,
+// which are those of the original word2vec tool.
+type Config struct {
+	Arch Arch
+
+	// Dim is the dimension of the word vectors.  Defaults to 100.
+	Dim int
+
+	// Window is the maximum distance between a word and the words used to predict it (or
+	// which it predicts).  The window used for each word is sampled uniformly from 1 to
+	// Window.  Defaults to 5.
+	Window int
+
+	// MinCount is the minimum number of times a word must occur in the corpus to be in
+	// the vocabulary.  Defaults to 5.
+	MinCount int
+
+	// Negative is the number of negative samples for each word.  Defaults to 5, or 0 if
+	// HierarchicalSoftmax is set.
+	Negative int
+
+	// HierarchicalSoftmax trains using hierarchical softmax over a Huffman tree of the
+	// vocabulary.  It can be used together with negative sampling by setting Negative.
+	HierarchicalSoftmax bool
+
+	// Sample is the threshold for down-sampling frequent words: words which make up more
+	// than this fraction of the corpus are randomly skipped.  Defaults to 1e-3, and a
+	// negative value disables down-sampling.
+	Sample float64
+
+	// Alpha is the initial learning rate, which decreases linearly as training proceeds.
+	// Defaults to 0.025 for SkipGram and 0.05 for CBOW.
+	Alpha float64
+
+	// Iterations is the number of passes over the corpus.  Defaults to 5.
+	Iterations int
+
+	// Workers is the number of goroutines used to train.  Workers update the weights
+	// without locking, as in the original word2vec tool, so training is only
+	// deterministic (for a given Seed) with one worker.  Defaults to GOMAXPROCS.
+	Workers int
+
+	// Freeze keeps the vectors of the words of the model being fine-tuned fixed, so that
+	// only the vectors of new words (and the output weights) are trained (see FromModel).
+	Freeze bool
+
+	// Seed seeds the random initialisation of the vectors and the random sampling done
+	// during training.
+	Seed int64
+
+	// Progress, if set, is called periodically during training.  It may be called
+	// concurrently by several workers.
+	Progress func(Progress)
+}
+
+// withDefaults returns c with zero fields set to their default values.
+func (c Config) withDefaults() Config {
+	if c.Dim <= 0 {
+		c.Dim = 100
+	}
+	if c.Window <= 0 {
+		c.Window = 5
+	}
+	if c.MinCount <= 0 {
+		c.MinCount = 5
+	}
+	if c.Negative <= 0 && !c.HierarchicalSoftmax {
+		c.Negative = 5
+	}
+	if c.Sample == 0 {
+		c.Sample = 1e-3
+	}
+	if c.Alpha <= 0 {
+		c.Alpha = 0.025
+		if c.Arch == CBOW {
+			c.Alpha = 0.05
+		}
+	}
+	if c.Iterations <= 0 {
+		c.Iterations = 5
+	}
+	c.Workers = numWorkers(c.Workers)
+	return c
+}
+
+func numWorkers(n int) int {
+	if n <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return n
+}
+
+// Progress is a type which reports the progress of training.
+type Progress struct {
+	Iteration  int     // current iteration (from 0)
+	Words      int64   // number of words trained so far, over all iterations
+	TotalWords int64   // total number of words to train, over all iterations
+	Alpha      float32 // current learning rate
+}
+
+// checkInterval is the number of words between each update of the learning rate (and
+// check of the context).
+const checkInterval = 10000
+
+// Sigmoids are computed using a table of expTableSize values between -maxExp and maxExp.
+const (
+	expTableSize = 1000
+	maxExp       = 6
+)
+
+var expTable = func() []float32 {
+	t := make([]float32, expTableSize)
+	for i := range t {
+		e := math.Exp((float64(i)/expTableSize*2 - 1) * maxExp)
+		t[i] = float32(e / (e + 1))
+	}
+	return t
+}()
+
+// sigmoid returns the sigmoid of f, which must be in (-maxExp, maxExp).
+func sigmoid(f float32) float32 {
+	return expTable[int((f+maxExp)*(expTableSize/maxExp/2))]
+}
+
+// unigramTableSize bounds the size of the table used to draw negative samples.
+const unigramTableSize = 1e8
+
+// Trainer is a type which trains word vectors for a vocabulary.
+type Trainer struct {
+	c     Config
+	vocab *Vocab
+
+	syn0    []float32 // word vectors
+	syn1    []float32 // hierarchical softmax weights of inner nodes
+	syn1neg []float32 // negative sampling weights of words
+
+	codes  [][]byte
+	points [][]int32
+	table  []int32
+
+	frozen int // vectors of words with index < frozen are not updated
+
+	words int64 // number of words trained, updated atomically
+	mu    sync.Mutex
+}
+
+// New returns a Trainer for the vocabulary v, with randomly initialised vectors.
+func New(v *Vocab, c Config) *Trainer {
+	return newTrainer(v, c.withDefaults(), false)
+}
+
+// newTrainer returns a Trainer for the vocabulary v.  If tied is set, the word vectors
+// are also used as the negative sampling weights.
+func newTrainer(v *Vocab, c Config, tied bool) *Trainer {
+	t := &Trainer{
+		c:     c,
+		vocab: v,
+		syn0:  make([]float32, v.Size()*c.Dim),
+	}
+
+	r := uint64(c.Seed) + 1
+	for i := range t.syn0 {
+		r = nextRandom(r)
+		t.syn0[i] = (float32(r&0xFFFF)/65536 - 0.5) / float32(c.Dim)
+	}
+
+	if c.HierarchicalSoftmax {
+		t.syn1 = make([]float32, v.Size()*c.Dim)
+		t.codes, t.points = v.huffman()
+	}
+	if c.Negative > 0 {
+		t.syn1neg = t.syn0
+		if !tied {
+			t.syn1neg = make([]float32, v.Size()*c.Dim)
+		}
+		t.table = unigramTable(v)
+	}
+	return t
+}
+
+// Vocab returns the vocabulary of the trainer.
+func (t *Trainer) Vocab() *Vocab {
+	return t.vocab
+}
+
+// Vector returns the vector of the word with index i, which is shared with the trainer.
+func (t *Trainer) Vector(i int) word2vec.Vector {
+	return t.vector(t.syn0, i)
+}
+
+func (t *Trainer) vector(w []float32, i int) word2vec.Vector {
+	return word2vec.Vector(w[i*t.c.Dim : (i+1)*t.c.Dim : (i+1)*t.c.Dim])
+}
+
+// nextRandom returns the next value of the linear congruential generator used by the
+// original word2vec tool, which is cheap enough to call for every sample.
+func nextRandom(r uint64) uint64 {
+	return r*25214903917 + 11
+}
+
+// unigramTable returns a table of word indices in which each word occurs in proportion
+// to its count raised to the power 3/4, from which negative samples are drawn.
+func unigramTable(v *Vocab) []int32 {
+	size := 100 * v.Size()
+	if size < 1e6 {
+		size = 1e6
+	}
+	if size > unigramTableSize {
+		size = unigramTableSize
+	}
+	table := make([]int32, size)
+	if v.Size() == 0 {
+		return table[:0]
+	}
+
+	var total float64
+	for _, n := range v.counts {
+		total += math.Pow(float64(n), 0.75)
+	}
+	i := 0
+	d := math.Pow(float64(v.counts[0]), 0.75) / total
+	for a := range table {
+		// Skip all the words whose share of the table ends before this entry, so that
+		// words with too small a share take no entries.
+		for (float64(a)+0.5)/float64(size) > d && i < v.Size()-1 {
+			i++
+			d += math.Pow(float64(v.counts[i]), 0.75) / total
+		}
+		table[a] = int32(i)
+	}
+	return table
+}
+
+// Train trains the vectors for Config.Iterations passes over the corpus c, which should
+// be the corpus the vocabulary was built from.  Training stops early if ctx is done,
+// in which case ctx.Err() is returned.
+func (t *Trainer) Train(ctx context.Context, c Corpus) error {
+	atomic.StoreInt64(&t.words, 0)
+	return run(t.c.Workers, func(i int) error {
+		return t.work(ctx, c, i)
+	})
+}
+
+// run calls f(i) for i in [0, n), each in its own goroutine, and returns the first error
+// (by i) returned by f.
+func run(n int, f func(i int) error) error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = f(i)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// worker holds the state of a goroutine training on a section of the corpus.
+type worker struct {
+	*Trainer
+	r     uint64
+	alpha float32
+	count int64 // words read since the last update
+	neu1  word2vec.Vector
+	neu1e word2vec.Vector
+}
+
+func (t *Trainer) newWorker(i int) *worker {
+	return &worker{
+		Trainer: t,
+		r:       uint64(t.c.Seed) + uint64(i),
+		alpha:   float32(t.c.Alpha),
+		neu1:    make(word2vec.Vector, t.c.Dim),
+		neu1e:   make(word2vec.Vector, t.c.Dim),
+	}
+}
+
+// work trains on section i of the corpus for each iteration.
+func (t *Trainer) work(ctx context.Context, c Corpus, i int) error {
+	w := t.newWorker(i)
+	sen := make([]int32, 0, maxSentenceLength)
+	for it := 0; it < t.c.Iterations; it++ {
+		sc, err := newScanner(c, i, t.c.Workers)
+		if err != nil {
+			return err
+		}
+
+		for {
+			sen, err = w.sentence(sc, sen[:0])
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if err := w.update(ctx, it); err != nil {
+				return err
+			}
+
+			for pos := range sen {
+				if t.c.Arch == CBOW {
+					w.cbow(sen, pos)
+				} else {
+					w.skipGram(sen, pos)
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+	}
+	atomic.AddInt64(&t.words, w.count)
+	return ctx.Err()
+}
+
+// update adds the words read by the worker to the total and updates the learning rate,
+// once every checkInterval words.  Returns an error if ctx is done.
+func (w *worker) update(ctx context.Context, it int) error {
+	if w.count < checkInterval {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	total := int64(w.c.Iterations) * w.vocab.total
+	words := atomic.AddInt64(&w.words, w.count)
+	w.count = 0
+	w.alpha = float32(w.c.Alpha * (1 - float64(words)/float64(total+1)))
+	if min := float32(w.c.Alpha * 1e-4); w.alpha < min {
+		w.alpha = min
+	}
+	w.progress(Progress{Iteration: it, Words: words, TotalWords: total, Alpha: w.alpha})
+	return nil
+}
+
+func (t *Trainer) progress(p Progress) {
+	if t.c.Progress == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.c.Progress(p)
+}
+
+// sentence reads the next sentence from sc into sen, skipping words which are not in the
+// vocabulary and down-sampling frequent words.
+func (w *worker) sentence(sc *scanner, sen []int32) ([]int32, error) {
+	for len(sen) < maxSentenceLength {
+		word, eol, err := sc.next()
+		if err != nil || (eol && len(sen) > 0) {
+			return sen, err
+		}
+		if eol {
+			continue
+		}
+		if i, ok := w.vocab.index[string(word)]; ok && w.keep(i) {
+			sen = append(sen, i)
+		}
+	}
+	return sen, nil
+}
+
+// keep counts an occurrence of the word with index i, and returns false if it should be
+// skipped by down-sampling.
+func (w *worker) keep(i int32) bool {
+	w.count++
+	if w.c.Sample <= 0 {
+		return true
+	}
+	w.r = nextRandom(w.r)
+	return keepProbability(w.vocab.counts[i], w.vocab.total, w.c.Sample) >= float64(w.r&0xFFFF)/65536
+}
+
+// keepProbability returns the probability that an occurrence of a word which occurs n
+// times in a corpus of total words is kept by down-sampling with the given threshold.
+func keepProbability(n, total int64, sample float64) float64 {
+	threshold := sample * float64(total)
+	return (math.Sqrt(float64(n)/threshold) + 1) * threshold / float64(n)
+}
+
+// window returns the bounds of a randomly shrunk window around position pos of sen.
+func (w *worker) window(sen []int32, pos int) (lo, hi int) {
+	return shrinkWindow(&w.r, w.c.Window, pos, len(sen))
+}
+
+// shrinkWindow returns the bounds of a window around position pos of n words, which
+// extends a random distance of 1 to window words either side.
+func shrinkWindow(r *uint64, window, pos, n int) (lo, hi int) {
+	*r = nextRandom(*r)
+	b := window - int(*r%uint64(window))
+	lo, hi = pos-b, pos+b+1
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > n {
+		hi = n
+	}
+	return lo, hi
+}
+
+// skipGram trains the vectors of the words in the window around position pos of sen to
+// predict the word at pos.
+func (w *worker) skipGram(sen []int32, pos int) {
+	lo, hi := w.window(sen, pos)
+	for c := lo; c < hi; c++ {
+		if c == pos {
+			continue
+		}
+		l1 := w.Vector(int(sen[c]))
+		for i := range w.neu1e {
+			w.neu1e[i] = 0
+		}
+		w.predict(l1, sen[pos])
+		if int(sen[c]) >= w.frozen {
+			l1.Add(1, w.neu1e)
+		}
+	}
+}
+
+// cbow trains the mean of the vectors of the words in the window around position pos of
+// sen to predict the word at pos.
+func (w *worker) cbow(sen []int32, pos int) {
+	lo, hi := w.window(sen, pos)
+	if hi-lo < 2 {
+		return
+	}
+	for i := range w.neu1 {
+		w.neu1[i], w.neu1e[i] = 0, 0
+	}
+	for c := lo; c < hi; c++ {
+		if c != pos {
+			w.neu1.Add(1, w.Vector(int(sen[c])))
+		}
+	}
+	n := float32(hi - lo - 1)
+	for i := range w.neu1 {
+		w.neu1[i] /= n
+	}
+
+	w.predict(w.neu1, sen[pos])
+	for c := lo; c < hi; c++ {
+		if c != pos && int(sen[c]) >= w.frozen {
+			w.Vector(int(sen[c])).Add(1, w.neu1e)
+		}
+	}
+}
+
+// predict updates the output weights to predict word from the hidden layer l1, and adds
+// the gradient for l1 to neu1e.
+func (w *worker) predict(l1 word2vec.Vector, word int32) {
+	if w.c.HierarchicalSoftmax {
+		for d, p := range w.points[word] {
+			l2 := w.vector(w.syn1, int(p))
+			f := l1.Dot(l2)
+			if f <= -maxExp || f >= maxExp {
+				continue
+			}
+			g := (1 - float32(w.codes[word][d]) - sigmoid(f)) * w.alpha
+			w.neu1e.Add(g, l2)
+			l2.Add(g, l1)
+		}
+	}
+
+	for d := 0; d <= w.c.Negative && w.c.Negative > 0; d++ {
+		target, label := word, float32(1)
+		if d > 0 {
+			w.r = nextRandom(w.r)
+			target, label = w.table[(w.r>>16)%uint64(len(w.table))], 0
+			if target == word {
+				continue
+			}
+		}
+		l2 := w.vector(w.syn1neg, int(target))
+		g := gradient(l1.Dot(l2), label, w.alpha)
+		w.neu1e.Add(g, l2)
+		l2.Add(g, l1)
+	}
+}
+
+// gradient returns the gradient of the log-likelihood of a negative sampling prediction
+// with score f and label (1 for the target word, 0 for a negative sample), scaled by the
+// learning rate alpha.
+func gradient(f, label, alpha float32) float32 {
+	switch {
+	case f > maxExp:
+		return (label - 1) * alpha
+	case f < -maxExp:
+		return label * alpha
+	}
+	return (label - sigmoid(f)) * alpha
+}
+
+// WriteBinary writes the trained vectors to w in the binary word2vec format, which can be
+// read using word2vec.FromReader.  Words are written in vocabulary order.
+func (t *Trainer) WriteBinary(w io.Writer) error {
+	return writeBinary(w, t.vocab.words, t.c.Dim, t.Vector)
+}
+
+// Model returns a *word2vec.Model of the trained vectors, loaded with the given options.
+func (t *Trainer) Model(opts ...word2vec.Option) (*word2vec.Model, error) {
+	return loadModel(t.WriteBinary, opts)
+}
+
+// writeBinary writes words and their vectors to w in the binary word2vec format.
+func writeBinary(w io.Writer, words []string, dim int, vector func(i int) word2vec.Vector) error {
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "%d %d\n", len(words), dim); err != nil {
+		return err
+	}
+	for i, word := range words {
+		if _, err := bw.WriteString(word); err != nil {
+			return err
+		}
+		if err := bw.WriteByte(' '); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, binary.LittleEndian, vector(i)); err != nil {
+			return err
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// loadModel loads the binary model data written by write.
+func loadModel(write func(io.Writer) error, opts []word2vec.Option) (*word2vec.Model, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(write(pw))
+	}()
+	m, err := word2vec.FromReader(pr, opts...)
+	pr.CloseWithError(io.ErrClosedPipe)
+	return m, err
+}
+
+// Train builds the vocabulary of the corpus c, and trains a model on it configured by
+// config.
+func Train(ctx context.Context, c Corpus, config Config) (*word2vec.Model, error) {
+	config = config.withDefaults()
+	v, err := BuildVocab(ctx, c, config.MinCount, config.Workers)
+	if err != nil {
+		return nil, err
+	}
+	t := New(v, config)
+	if err := t.Train(ctx, c); err != nil {
+		return nil, err
+	}
+	return t.Model()
+}
@@ -0,0 +1,142 @@
+package train
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"code.sajari.com/word2vec"
+)
+
+// testCorpus returns a corpus of sentences, each made of words from one of the topics,
+// joined by common words.  The words of a topic are its letters followed by a digit (so
+// topic "ab" has words a0...a9 and b0...b9).
+func testCorpus(sentences int, topics ...string) *strings.Reader {
+	r := rand.New(rand.NewSource(1))
+	b := &strings.Builder{}
+	for i := 0; i < sentences; i++ {
+		topic := topics[r.Intn(len(topics))]
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteString(" the ")
+			}
+			fmt.Fprintf(b, "%c%d", topic[r.Intn(len(topic))], r.Intn(10))
+		}
+		b.WriteByte('\n')
+	}
+	return strings.NewReader(b.String())
+}
+
+// topicSimilarity returns the mean similarity of pairs of words from topics x and y.
+func topicSimilarity(t *testing.T, m *word2vec.Model, x, y string) float32 {
+	var sum, n float32
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			if x == y && i == j {
+				continue
+			}
+			a, b := word2vec.Expr{}, word2vec.Expr{}
+			a.Add(1, fmt.Sprintf("%s%d", x, i))
+			b.Add(1, fmt.Sprintf("%s%d", y, j))
+			s, err := m.Cos(a, b)
+			if err != nil {
+				t.Fatalf("unexpected error from Cos: %v", err)
+			}
+			sum += s
+			n++
+		}
+	}
+	return sum / n
+}
+
+func TestTrain(t *testing.T) {
+	c := testCorpus(2000, "a", "b")
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"skip-gram negative", Config{Arch: SkipGram}},
+		{"skip-gram hs", Config{Arch: SkipGram, HierarchicalSoftmax: true}},
+		{"cbow negative", Config{Arch: CBOW}},
+		{"cbow hs", Config{Arch: CBOW, HierarchicalSoftmax: true}},
+	}
+
+	for _, tt := range tests {
+		tt.config.Dim = 20
+		tt.config.Workers = 1
+		tt.config.Seed = 1
+		var calls int
+		tt.config.Progress = func(Progress) { calls++ }
+
+		m, err := Train(context.Background(), c, tt.config)
+		if err != nil {
+			t.Errorf("[%s] unexpected error from Train: %v", tt.name, err)
+			continue
+		}
+		if m.Size() != 21 || m.Dim() != 20 {
+			t.Errorf("[%s] model size %d, dim %d, expected size 21, dim 20", tt.name, m.Size(), m.Dim())
+		}
+		if calls == 0 {
+			t.Errorf("[%s] expected progress to be reported", tt.name)
+		}
+		same, different := topicSimilarity(t, m, "a", "a"), topicSimilarity(t, m, "a", "b")
+		if same < different+0.3 {
+			t.Errorf("[%s] mean similarity within topics %v, between topics %v, expected within > between + 0.3", tt.name, same, different)
+		}
+	}
+}
+
+func TestTrainerWriteBinary(t *testing.T) {
+	c := testCorpus(100, "a", "b")
+	v, err := BuildVocab(context.Background(), c, 1, 2)
+	if err != nil {
+		t.Fatalf("unexpected error from BuildVocab: %v", err)
+	}
+	tr := New(v, Config{Dim: 8, Iterations: 1, Workers: 1, Seed: 2})
+	if err := tr.Train(context.Background(), c); err != nil {
+		t.Fatalf("unexpected error from Train: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tr.WriteBinary(buf); err != nil {
+		t.Fatalf("unexpected error from WriteBinary: %v", err)
+	}
+	m, err := word2vec.FromReader(buf, word2vec.RawVectors())
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+	if m.Size() != v.Size() {
+		t.Errorf("m.Size() = %d, expected %d", m.Size(), v.Size())
+	}
+
+	words := make([]string, v.Size())
+	for i := range words {
+		words[i] = v.Word(i)
+	}
+	vecs := m.Map(words)
+	for i, w := range words {
+		for j, x := range tr.Vector(i) {
+			if vecs[w][j] != x {
+				t.Errorf("vector %q = %v, expected %v", w, vecs[w], tr.Vector(i))
+				break
+			}
+		}
+	}
+
+	mm, err := tr.Model()
+	if err != nil {
+		t.Fatalf("unexpected error from Model: %v", err)
+	}
+	if mm.Fingerprint() != m.Fingerprint() {
+		t.Errorf("tr.Model().Fingerprint() = %v, expected %v", mm.Fingerprint(), m.Fingerprint())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tr.Train(ctx, c); err != context.Canceled {
+		t.Errorf("Train() error = %v, expected %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,208 @@
+package train
+
+import (
+	"context"
+	"io"
+	"sort"
+)
+
+// Vocab is the vocabulary of a corpus: the words which occur in it at least the minimum
+// number of times, sorted by descending count.  Vocabularies extended from a model (see
+// FromModel) start with the words of the model, in the order of the model.
+type Vocab struct {
+	words  []string
+	counts []int64
+	index  map[string]int32
+	total  int64
+}
+
+// Size returns the number of words in the vocabulary.
+func (v *Vocab) Size() int {
+	return len(v.words)
+}
+
+// Word returns the word with index i.
+func (v *Vocab) Word(i int) string {
+	return v.words[i]
+}
+
+// Count returns the number of times the word with index i occurs in the corpus.
+func (v *Vocab) Count(i int) int64 {
+	return v.counts[i]
+}
+
+// Index returns the index of the word w, and false if w is not in the vocabulary.
+func (v *Vocab) Index(w string) (int, bool) {
+	i, ok := v.index[w]
+	return int(i), ok
+}
+
+// Total returns the number of occurrences of words in the vocabulary in the corpus.
+func (v *Vocab) Total() int64 {
+	return v.total
+}
+
+// BuildVocab counts the words in the corpus c using the given number of goroutines (or
+// GOMAXPROCS if workers <= 0), and returns the vocabulary of words which occur at least
+// minCount times.
+func BuildVocab(ctx context.Context, c Corpus, minCount, workers int) (*Vocab, error) {
+	counts, err := countCorpus(ctx, c, workers)
+	if err != nil {
+		return nil, err
+	}
+	return newVocab(counts, minCount), nil
+}
+
+// countCorpus counts the words in the corpus c using the given number of goroutines.
+func countCorpus(ctx context.Context, c Corpus, workers int) (map[string]int64, error) {
+	workers = numWorkers(workers)
+	counts := make([]map[string]int64, workers)
+	err := run(workers, func(i int) (err error) {
+		counts[i], err = countWords(ctx, c, i, workers)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range counts[1:] {
+		for w, n := range m {
+			counts[0][w] += n
+		}
+	}
+	return counts[0], nil
+}
+
+// countWords counts the words in section i of n sections of c.
+func countWords(ctx context.Context, c Corpus, i, n int) (map[string]int64, error) {
+	sc, err := newScanner(c, i, n)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	for k := 0; ; k++ {
+		if k%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		w, eol, err := sc.next()
+		if err == io.EOF {
+			return counts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !eol {
+			counts[string(w)]++
+		}
+	}
+}
+
+// newVocab returns the vocabulary of the words in counts which occur at least minCount
+// times, sorted by descending count (and then by word).
+func newVocab(counts map[string]int64, minCount int) *Vocab {
+	return extendVocab(nil, counts, minCount)
+}
+
+// extendVocab returns a vocabulary of the given words (with their counts, which may be
+// zero), followed by the other words in counts which occur at least minCount times,
+// sorted by descending count (and then by word).
+func extendVocab(words []string, counts map[string]int64, minCount int) *Vocab {
+	v := &Vocab{words: append([]string(nil), words...)}
+	known := make(map[string]bool, len(words))
+	for _, w := range words {
+		known[w] = true
+	}
+
+	var extra []string
+	for w, n := range counts {
+		if n >= int64(minCount) && !known[w] {
+			extra = append(extra, w)
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool {
+		x, y := counts[extra[i]], counts[extra[j]]
+		if x != y {
+			return x > y
+		}
+		return extra[i] < extra[j]
+	})
+	v.words = append(v.words, extra...)
+
+	v.counts = make([]int64, len(v.words))
+	v.index = make(map[string]int32, len(v.words))
+	for i, w := range v.words {
+		v.counts[i] = counts[w]
+		v.index[w] = int32(i)
+		v.total += counts[w]
+	}
+	return v
+}
+
+// huffman builds a Huffman tree over the words of the vocabulary by count, as used by
+// hierarchical softmax.  For each word it returns the code (the branches taken from the
+// root to the word) and the points (the indices of the inner nodes on the path, starting
+// at the root).  The tree is built as in the original word2vec tool, from the words
+// sorted by descending count.
+func (v *Vocab) huffman() (codes [][]byte, points [][]int32) {
+	n := len(v.words)
+	codes = make([][]byte, n)
+	points = make([][]int32, n)
+	if n < 2 {
+		return codes, points
+	}
+
+	// Leaves are sorted by descending count, and then followed by the inner nodes.
+	leaves := make([]int, n)
+	for i := range leaves {
+		leaves[i] = i
+	}
+	sort.SliceStable(leaves, func(i, j int) bool {
+		return v.counts[leaves[i]] > v.counts[leaves[j]]
+	})
+	count := make([]int64, 2*n-1)
+	for i, w := range leaves {
+		count[i] = v.counts[w]
+	}
+	for i := n; i < len(count); i++ {
+		count[i] = 1 << 62
+	}
+	binary := make([]byte, 2*n-1)
+	parent := make([]int32, 2*n-1)
+
+	// Leaves are taken from the end of the sorted counts, inner nodes in the order they
+	// are created, so the two smallest nodes are always at pos1 or pos2.
+	pos1, pos2 := n-1, n
+	smallest := func() int {
+		if pos1 >= 0 && count[pos1] < count[pos2] {
+			pos1--
+			return pos1 + 1
+		}
+		pos2++
+		return pos2 - 1
+	}
+	for a := 0; a < n-1; a++ {
+		min1, min2 := smallest(), smallest()
+		count[n+a] = count[min1] + count[min2]
+		parent[min1] = int32(n + a)
+		parent[min2] = int32(n + a)
+		binary[min2] = 1
+	}
+
+	root := int32(2*n - 2)
+	for a, w := range leaves {
+		var code []byte
+		var point []int32
+		for b := int32(a); b != root; b = parent[b] {
+			code = append(code, binary[b])
+			point = append(point, parent[b]-int32(n))
+		}
+		// Reverse, so the path starts at the root.
+		for i, j := 0, len(code)-1; i < j; i, j = i+1, j-1 {
+			code[i], code[j] = code[j], code[i]
+			point[i], point[j] = point[j], point[i]
+		}
+		codes[w], points[w] = code, point
+	}
+	return codes, points
+}
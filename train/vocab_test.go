@@ -0,0 +1,103 @@
+package train
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildVocab(t *testing.T) {
+	c := strings.NewReader("a b c a b a\nd a b\nc e")
+	tests := []struct {
+		minCount int
+		words    []string
+		counts   []int64
+	}{
+		{1, []string{"a", "b", "c", "d", "e"}, []int64{4, 3, 2, 1, 1}},
+		{2, []string{"a", "b", "c"}, []int64{4, 3, 2}},
+		{5, nil, []int64{}},
+	}
+
+	for _, tt := range tests {
+		for _, workers := range []int{1, 3} {
+			v, err := BuildVocab(context.Background(), c, tt.minCount, workers)
+			if err != nil {
+				t.Errorf("[%d] unexpected error from BuildVocab: %v", tt.minCount, err)
+				continue
+			}
+			if !reflect.DeepEqual(v.words, tt.words) || !reflect.DeepEqual(v.counts, tt.counts) {
+				t.Errorf("[%d] BuildVocab() = %v %v, expected %v %v", tt.minCount, v.words, v.counts, tt.words, tt.counts)
+			}
+			var total int64
+			for _, n := range tt.counts {
+				total += n
+			}
+			if v.Total() != total {
+				t.Errorf("[%d] v.Total() = %d, expected %d", tt.minCount, v.Total(), total)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := BuildVocab(ctx, c, 1, 1); err != context.Canceled {
+		t.Errorf("BuildVocab() error = %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestHuffman(t *testing.T) {
+	v := newVocab(map[string]int64{"a": 10, "b": 6, "c": 3, "d": 2, "e": 1}, 1)
+	codes, points := v.huffman()
+
+	// Codes are prefix-free, and more frequent words have codes no longer than less
+	// frequent words.
+	for i, x := range codes {
+		if len(points[i]) != len(x) {
+			t.Errorf("len(points[%d]) = %d, expected %d", i, len(points[i]), len(x))
+		}
+		if points[i][0] != int32(v.Size()-2) {
+			t.Errorf("points[%d][0] = %d, expected root %d", i, points[i][0], v.Size()-2)
+		}
+		if i > 0 && len(x) < len(codes[i-1]) {
+			t.Errorf("len(codes[%d]) = %d, expected >= %d", i, len(x), len(codes[i-1]))
+		}
+		for j, y := range codes {
+			if i != j && len(x) <= len(y) && reflect.DeepEqual(x, y[:len(x)]) {
+				t.Errorf("codes[%d] = %v is a prefix of codes[%d] = %v", i, x, j, y)
+			}
+		}
+	}
+
+	// The expected length of a code is the Huffman optimum.
+	var length int64
+	for i, x := range codes {
+		length += v.Count(i) * int64(len(x))
+	}
+	if length != 10*1+6*2+3*3+2*4+1*4 {
+		t.Errorf("total code length = %d, expected %d", length, 10*1+6*2+3*3+2*4+1*4)
+	}
+}
+
+func TestExtendVocab(t *testing.T) {
+	v := extendVocab([]string{"d", "b"}, map[string]int64{"a": 10, "b": 6, "c": 3, "d": 2, "e": 1}, 1)
+	if expected := []string{"d", "b", "a", "c", "e"}; !reflect.DeepEqual(v.words, expected) {
+		t.Errorf("extendVocab() = %v, expected %v", v.words, expected)
+	}
+
+	// The Huffman tree is built from the words sorted by count, whatever their order in
+	// the vocabulary.
+	codes, _ := v.huffman()
+	var length int64
+	for i, x := range codes {
+		for j, y := range codes {
+			if v.Count(i) > v.Count(j) && len(x) > len(y) {
+				t.Errorf("len(codes[%d]) = %d, expected <= len(codes[%d]) = %d", i, len(x), j, len(y))
+			}
+		}
+		length += v.Count(i) * int64(len(x))
+	}
+	if length != 10*1+6*2+3*3+2*4+1*4 {
+		t.Errorf("total code length = %d, expected %d", length, 10*1+6*2+3*3+2*4+1*4)
+	}
+}
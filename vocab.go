@@ -0,0 +1,102 @@
+package word2vec
+
+import "fmt"
+
+// vocabulary is an interface which defines the mapping between words and their
+// index in a Model.  Indices are contiguous, starting at 0.
+type vocabulary interface {
+	// id returns the index of w, and whether w is in the vocabulary.
+	id(w string) (int, bool)
+
+	// word returns the word with index i.
+	word(i int) string
+
+	// size returns the number of words in the vocabulary.
+	size() int
+}
+
+// mapVocab is an in-memory vocabulary.
+type mapVocab struct {
+	words []string
+	ids   map[string]int
+}
+
+func newMapVocab(size int) *mapVocab {
+	return &mapVocab{
+		words: make([]string, 0, size),
+		ids:   make(map[string]int, size),
+	}
+}
+
+// add appends w to the vocabulary and returns its index.
+func (v *mapVocab) add(w string) int {
+	i := len(v.words)
+	v.words = append(v.words, w)
+	v.ids[w] = i
+	return i
+}
+
+func (v *mapVocab) id(w string) (int, bool) {
+	i, ok := v.ids[w]
+	return i, ok
+}
+
+func (v *mapVocab) word(i int) string { return v.words[i] }
+func (v *mapVocab) size() int         { return len(v.words) }
+
+// ID returns the integer ID of w in the model.  IDs are contiguous, starting at 0, and
+// follow the order of the words in the model data (which is usually by descending
+// frequency).  Returns an error if w is not in the model.
+func (m *Model) ID(w string) (int, error) {
+	i, ok := m.vocab.id(w)
+	if !ok {
+		return 0, &NotFoundError{w}
+	}
+	return i, nil
+}
+
+// Word returns the word with the given ID.  Returns an error if the ID is out of range.
+func (m *Model) Word(id int) (string, error) {
+	if err := m.checkID(id); err != nil {
+		return "", err
+	}
+	return m.vocab.word(id), nil
+}
+
+// Rank returns the position of w in the model data, starting at 1.  For models sorted
+// by frequency (such as those written by the word2vec tool) this is the frequency rank
+// of w.  Returns an error if w is not in the model.
+func (m *Model) Rank(w string) (int, error) {
+	i, err := m.ID(w)
+	if err != nil {
+		return 0, err
+	}
+	return i + 1, nil
+}
+
+// VectorByID returns the vector for the word with the given ID.  The returned vector may
+// be shared with the model and must not be modified.  Returns an error if the ID is out of
+// range.
+func (m *Model) VectorByID(id int) (Vector, error) {
+	if err := m.checkID(id); err != nil {
+		return nil, err
+	}
+	return m.row(id), nil
+}
+
+// Range calls f for each word in the model in ID order, with the word's ID and vector
+// (which must not be modified).  If f returns false, Range stops the iteration.
+func (m *Model) Range(f func(id int, w string, v Vector) bool) {
+	for i := 0; i < m.vocab.size(); i++ {
+		if !f(i, m.vocab.word(i), m.row(i)) {
+			return
+		}
+	}
+}
+
+func (m *Model) checkID(id int) error {
+	if id < 0 || id >= m.vocab.size() {
+		return fmt.Errorf("word id out of range: %d", id)
+	}
+	return nil
+}
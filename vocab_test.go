@@ -0,0 +1,84 @@
+package word2vec
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestVocabularyOrder(t *testing.T) {
+	words := []string{"the", "of", "and", "zebra"}
+	vecs := []Vector{{1, 0}, {0, 1}, {0.6, 0.8}, {-1, 0}}
+
+	m, err := FromReader(bytes.NewReader(testModelData(t, words, vecs)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	for i, w := range words {
+		id, err := m.ID(w)
+		if err != nil {
+			t.Errorf("unexpected error from m.ID(%q): %v", w, err)
+		}
+		if id != i {
+			t.Errorf("m.ID(%q) = %d, expected %d", w, id, i)
+		}
+
+		rank, err := m.Rank(w)
+		if err != nil {
+			t.Errorf("unexpected error from m.Rank(%q): %v", w, err)
+		}
+		if rank != i+1 {
+			t.Errorf("m.Rank(%q) = %d, expected %d", w, rank, i+1)
+		}
+
+		word, err := m.Word(i)
+		if err != nil {
+			t.Errorf("unexpected error from m.Word(%d): %v", i, err)
+		}
+		if word != w {
+			t.Errorf("m.Word(%d) = %q, expected %q", i, word, w)
+		}
+
+		v, err := m.VectorByID(i)
+		if err != nil {
+			t.Errorf("unexpected error from m.VectorByID(%d): %v", i, err)
+		}
+		if !reflect.DeepEqual(v, m.Map([]string{w})[w]) {
+			t.Errorf("m.VectorByID(%d) = %v, expected %v", i, v, m.Map([]string{w})[w])
+		}
+	}
+
+	if _, err := m.ID("missing"); err == nil {
+		t.Errorf("expected error from m.ID(\"missing\")")
+	}
+	for _, id := range []int{-1, len(words)} {
+		if _, err := m.Word(id); err == nil {
+			t.Errorf("expected error from m.Word(%d)", id)
+		}
+		if _, err := m.VectorByID(id); err == nil {
+			t.Errorf("expected error from m.VectorByID(%d)", id)
+		}
+	}
+
+	var got []string
+	m.Range(func(id int, w string, v Vector) bool {
+		if id != len(got) {
+			t.Errorf("Range: id = %d, expected %d", id, len(got))
+		}
+		got = append(got, w)
+		return true
+	})
+	if !reflect.DeepEqual(got, words) {
+		t.Errorf("Range: words = %v, expected %v", got, words)
+	}
+
+	got = got[:0]
+	m.Range(func(id int, w string, v Vector) bool {
+		got = append(got, w)
+		return id < 1
+	})
+	if !reflect.DeepEqual(got, words[:2]) {
+		t.Errorf("Range (stopped): words = %v, expected %v", got, words[:2])
+	}
+}
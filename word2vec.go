@@ -3,10 +3,10 @@
 package word2vec // import "code.sajari.com/word2vec"
 
 import (
-	"bufio"
-	"encoding/binary"
+	"context"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 )
 
@@ -14,7 +14,17 @@ import (
 // and Mapper interfaces.
 type Model struct {
 	dim   int
-	words map[string]Vector
+	vocab vocabulary
+	store storage   // vectors, in vocabulary order
+	norms []float32 // norms of the vectors in the model data, nil if not known
+	raw   bool      // true if vecs are not normalised (see RawVectors)
+	sim   Similarity
+	sub   *subwords // n-gram vectors for words not in vocab, nil if not used (see Subwords)
+
+	fp       [16]byte // see Fingerprint
+	manifest *Manifest
+
+	closer io.Closer // releases the backing data, if any (see OpenMmap)
 }
 
 var (
@@ -23,54 +33,106 @@ var (
 )
 
 // FromReader creates a Model using the binary model data provided by the io.Reader.
-func FromReader(r io.Reader) (*Model, error) {
-	br := bufio.NewReader(r)
-	var size, dim int
-	n, err := fmt.Fscanln(r, &size, &dim)
+// Errors in the model data are reported using ParseError.
+func FromReader(r io.Reader, opts ...Option) (*Model, error) {
+	return fromBinary(context.Background(), r, newOptions(opts))
+}
+
+func fromBinary(ctx context.Context, r io.Reader, o *options) (*Model, error) {
+	p := newParser(r, o)
+	size, dim, err := p.binaryHeader()
 	if err != nil {
 		return nil, err
 	}
-	if n != 2 {
-		return nil, fmt.Errorf("could not extract size/dim from binary model data")
+
+	b := newBuilder(p.o, p.prealloc(size, dim, 4*dim+2), dim)
+	if err := p.stream(ctx, b, size, p.readBinary(size, dim), decodeBinary(dim)); err != nil {
+		return nil, err
 	}
+	return b.model()
+}
 
-	m := &Model{
-		words: make(map[string]Vector, size),
-		dim:   dim,
+// Close releases any resources held by the model (see OpenMmap).  The model must not
+// be used after it has been closed.
+func (m *Model) Close() error {
+	if m.closer == nil {
+		return nil
 	}
+	return m.closer.Close()
+}
 
-	raw := make([]float32, size*dim)
+// row returns the vector for the word with index i.
+func (m *Model) row(i int) Vector {
+	return m.store.vector(i)
+}
 
-	for i := 0; i < size; i++ {
-		w, err := br.ReadString(' ')
-		if err != nil {
-			return nil, err
-		}
-		w = w[:len(w)-1]
+// norm returns the norm of the vector for the word with index i in the model data.
+// If the norm is not known then the vector is assumed to be normalised.
+func (m *Model) norm(i int) float32 {
+	if m.norms == nil {
+		return 1
+	}
+	return m.norms[i]
+}
 
-		v := Vector(raw[dim*i : m.dim*(i+1)])
-		if err := binary.Read(br, binary.LittleEndian, v); err != nil {
-			return nil, err
+// scale returns the factor by which the vector for the word with index i must be
+// multiplied to obtain the vector used by the model's similarity function.
+func (m *Model) scale(i int) float32 {
+	return m.scaleNorm(m.norm(i))
+}
+
+// scaleNorm returns the factor by which a vector with norm n in the model data must be
+// multiplied to obtain the vector used by the model's similarity function.
+func (m *Model) scaleNorm(n float32) float32 {
+	switch {
+	case m.raw && m.sim == Cosine:
+		if n != 0 {
+			return 1 / n
 		}
+		return 0
+	case !m.raw && m.sim == DotProduct:
+		return n
+	}
+	return 1
+}
 
-		v.Normalise()
+// Similarity is a type which represents a function used by a Model to score the
+// similarity of vectors.
+type Similarity int
 
-		m.words[w] = v
+// Similarity functions.
+const (
+	// Cosine scores vectors by their cosine similarity, i.e. the dot product of the
+	// normalised vectors.  This is the default.
+	Cosine Similarity = iota
 
-		b, err := br.ReadByte()
-		if err != nil {
-			if i == size-1 && err == io.EOF {
-				break
-			}
-			return nil, err
-		}
-		if b != byte('\n') {
-			if err := br.UnreadByte(); err != nil {
-				return nil, err
-			}
-		}
+	// DotProduct scores vectors by the dot product of the vectors as they appear in the
+	// model data, so that words with larger vector norms score more highly.
+	DotProduct
+)
+
+// WithSimilarity returns a Model which shares the data of m, but uses the similarity
+// function s in Cos, Coses, CosN and Eval.  Note that closing either model releases
+// the shared data.
+func (m *Model) WithSimilarity(s Similarity) *Model {
+	c := *m
+	c.sim = s
+	return &c
+}
+
+// Similarity returns the similarity function used by the model.
+func (m *Model) Similarity() Similarity {
+	return m.sim
+}
+
+// Norm returns the norm of the vector for w as it appeared in the model data (i.e. before
+// normalisation).  Returns an error if w is not in the model.
+func (m *Model) Norm(w string) (float32, error) {
+	i, ok := m.vocab.id(w)
+	if !ok {
+		return 0, &NotFoundError{w}
 	}
-	return m, nil
+	return m.norm(i), nil
 }
 
 // Vector is a type which represents a word vector.
@@ -122,6 +184,33 @@ func AddWeight(e Expr, weights []float32, words []string) {
 	}
 }
 
+// PhraseSeparator joins the words of phrases in models trained on text rewritten by
+// word2phrase, and is the default separator of train.PhraseConfig (see AddPhrases).
+const PhraseSeparator = "_"
+
+// maxPhraseWords is the maximum number of words joined into a phrase by AddPhrases.
+const maxPhraseWords = 8
+
+// AddPhrases is a convenience method for adding multiple words to an Expr, in which runs
+// of consecutive words which are in the model as a phrase (joined by sep, such as
+// "new_york" with PhraseSeparator) are added as the phrase rather than as individual
+// words.  Longer phrases are preferred, scanning the words from left to right.
+func AddPhrases(e Expr, m *Model, sep string, weight float32, words []string) {
+	for i := 0; i < len(words); {
+		j := i + maxPhraseWords
+		if j > len(words) {
+			j = len(words)
+		}
+		for ; j > i+1; j-- {
+			if _, ok := m.vocab.id(strings.Join(words[i:j], sep)); ok {
+				break
+			}
+		}
+		e.Add(weight, strings.Join(words[i:j], sep))
+		i = j
+	}
+}
+
 // Coser is an interface which defines methods which can evaluate cosine similarity
 // between Exprs.
 type Coser interface {
@@ -137,7 +226,7 @@ type Coser interface {
 
 // Size returns the number of words in the model.
 func (m *Model) Size() int {
-	return len(m.words)
+	return m.vocab.size()
 }
 
 // Dim returns the dimention of the vectors in the model.
@@ -152,18 +241,22 @@ type Mapper interface {
 }
 
 // Map returns a mapping word -> Vector for each word in `words`.
-// Unknown words are ignored.
+// Unknown words are ignored, unless the model synthesises their vectors
+// from subwords (see Subwords).  The returned vectors may be shared with the
+// model and must not be modified.  Vectors are normalised unless the model
+// was loaded with RawVectors.
 func (m *Model) Map(words []string) map[string]Vector {
 	result := make(map[string]Vector)
 	for _, w := range words {
-		if v, ok := m.words[w]; ok {
+		if v, _, ok := m.vector(w); ok {
 			result[w] = v
 		}
 	}
 	return result
 }
 
-// Cos returns the cosine similarity of the given expressions.
+// Cos returns the cosine similarity of the given expressions (or their dot
+// product, see WithSimilarity).
 func (m *Model) Cos(a, b Expr) (float32, error) {
 	u, err := a.Eval(m)
 	if err != nil {
@@ -192,17 +285,22 @@ func (m *Model) Coses(pairs [][2]Expr) ([]float32, error) {
 }
 
 // Eval constructs a vector by evaluating the expression
-// vector.  Returns an error if a word is not in the model.
+// vector.  Returns an error if a word is not in the model (and
+// its vector cannot be synthesised from subwords, see Subwords).
+// The result is normalised unless the model uses DotProduct
+// similarity.
 func (m *Model) Eval(expr Expr) (Vector, error) {
 	v := Vector(make([]float32, m.dim))
 	for w, c := range expr {
-		u, ok := m.words[w]
+		u, n, ok := m.vector(w)
 		if !ok {
 			return nil, &NotFoundError{w}
 		}
-		v.Add(c, u)
+		v.Add(c*m.scaleNorm(n), u)
+	}
+	if m.sim == Cosine {
+		v.Normalise()
 	}
-	v.Normalise()
 	return v, nil
 }
 
@@ -225,20 +323,27 @@ func (m *Model) CosN(e Expr, n int) ([]Match, error) {
 		return nil, err
 	}
 
-	v.Normalise()
+	if m.sim == Cosine {
+		v.Normalise()
+	}
 	return m.cosineN(v, n), nil
 }
 
 // cosineN is a method which returns a list of `n` most similar vectors to `v` in the model.
 func (m *Model) cosineN(v Vector, n int) []Match {
+	dot := func(i int) float32 { return m.store.dot(v, i) }
+	if q, ok := m.store.(queryStorage); ok {
+		dot = q.query(v)
+	}
+
 	r := make([]Match, n)
-	for w, u := range m.words {
-		score := v.Dot(u)
-		p := Match{w, score}
+	for i := 0; i < m.vocab.size(); i++ {
+		score := dot(i) * m.scale(i)
 		// TODO(dhowden): MaxHeap would be better here if n is large.
-		if r[n-1].Score > p.Score {
+		if r[n-1].Score > score {
 			continue
 		}
+		p := Match{m.vocab.word(i), score}
 		r[n-1] = p
 		for j := n - 2; j >= 0; j-- {
 			if r[j].Score > p.Score {
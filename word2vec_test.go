@@ -151,3 +151,124 @@ func TestAddWeight(t *testing.T) {
 		t.Errorf("x = %v, y = %v", x, y)
 	}
 }
+
+func TestAddPhrases(t *testing.T) {
+	words := []string{"new", "york", "new_york", "los_angeles", "new_york_city_hall", "city", "san-francisco"}
+	vecs := make([]Vector, len(words))
+	for i := range vecs {
+		vecs[i] = Vector{1, float32(i)}
+	}
+	m, err := FromReader(bytes.NewReader(testModelData(t, words, vecs)))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	tests := []struct {
+		sep   string
+		words []string
+		expr  Expr
+	}{
+		{PhraseSeparator, []string{"new", "york"}, Expr{"new_york": 1}},
+		{PhraseSeparator, []string{"new", "york", "city"}, Expr{"new_york": 1, "city": 1}},
+		{PhraseSeparator, []string{"new", "york", "city", "hall"}, Expr{"new_york_city_hall": 1}},
+		{PhraseSeparator, []string{"los", "angeles", "new"}, Expr{"los_angeles": 1, "new": 1}},
+		{PhraseSeparator, []string{"york", "new", "unknown"}, Expr{"york": 1, "new": 1, "unknown": 1}},
+		{PhraseSeparator, []string{"san", "francisco"}, Expr{"san": 1, "francisco": 1}},
+		{"-", []string{"san", "francisco"}, Expr{"san-francisco": 1}},
+		{"-", []string{"new", "york"}, Expr{"new": 1, "york": 1}},
+	}
+
+	for _, tt := range tests {
+		e := Expr{}
+		AddPhrases(e, m, tt.sep, 1, tt.words)
+		if !reflect.DeepEqual(e, tt.expr) {
+			t.Errorf("AddPhrases(%q, %q) = %v, expected %v", tt.sep, tt.words, e, tt.expr)
+		}
+	}
+}
+
+func approxEqual(x, y float32) bool {
+	d := x - y
+	return d < 1e-5 && d > -1e-5
+}
+
+func TestRawVectors(t *testing.T) {
+	data := testModelData(t, []string{"hello", "world", "again"}, []Vector{{0, 2}, {3, 4}, {1, 0}})
+
+	m, err := FromReader(bytes.NewReader(data), RawVectors())
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	expected := map[string]Vector{
+		"hello": Vector{0, 2},
+		"world": Vector{3, 4},
+	}
+	if vecs := m.Map([]string{"hello", "world"}); !reflect.DeepEqual(vecs, expected) {
+		t.Errorf("m.Map() = %v, expected %v", vecs, expected)
+	}
+
+	norm, err := m.Norm("world")
+	if err != nil {
+		t.Fatalf("unexpected error from m.Norm(): %v", err)
+	}
+	if !approxEqual(norm, 5) {
+		t.Errorf("m.Norm(\"world\") = %v, expected 5", norm)
+	}
+
+	// Cosine similarity is unaffected by RawVectors.
+	c, err := m.Cos(Expr{"hello": 1}, Expr{"world": 1})
+	if err != nil {
+		t.Fatalf("unexpected error from m.Cos(): %v", err)
+	}
+	if !approxEqual(c, 0.8) {
+		t.Errorf("m.Cos() = %v, expected 0.8", c)
+	}
+}
+
+func TestDotProduct(t *testing.T) {
+	data := testModelData(t, []string{"hello", "world", "again"}, []Vector{{0, 2}, {3, 4}, {1, 0}})
+
+	for _, raw := range []bool{false, true} {
+		var opts []Option
+		if raw {
+			opts = append(opts, RawVectors())
+		}
+		m, err := FromReader(bytes.NewReader(data), opts...)
+		if err != nil {
+			t.Fatalf("unexpected error from FromReader: %v", err)
+		}
+
+		dm := m.WithSimilarity(DotProduct)
+		if dm.Similarity() != DotProduct {
+			t.Errorf("dm.Similarity() = %v, expected %v", dm.Similarity(), DotProduct)
+		}
+
+		c, err := dm.Cos(Expr{"hello": 1}, Expr{"world": 1})
+		if err != nil {
+			t.Fatalf("unexpected error from dm.Cos(): %v", err)
+		}
+		if !approxEqual(c, 8) {
+			t.Errorf("[raw: %t] dm.Cos() = %v, expected 8", raw, c)
+		}
+
+		// By cosine similarity "hello" is closest to itself, but "world" has the
+		// larger dot product.
+		matches, err := dm.CosN(Expr{"hello": 1}, 2)
+		if err != nil {
+			t.Fatalf("unexpected error from dm.CosN(): %v", err)
+		}
+		if len(matches) != 2 || matches[0].Word != "world" || !approxEqual(matches[0].Score, 8) ||
+			matches[1].Word != "hello" || !approxEqual(matches[1].Score, 4) {
+			t.Errorf("[raw: %t] dm.CosN() = %v, expected [{world 8} {hello 4}]", raw, matches)
+		}
+
+		matches, err = m.CosN(Expr{"hello": 1}, 1)
+		if err != nil {
+			t.Fatalf("unexpected error from m.CosN(): %v", err)
+		}
+		if matches[0].Word != "hello" {
+			t.Errorf("[raw: %t] m.CosN() = %v, expected hello", raw, matches)
+		}
+	}
+}
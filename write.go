@@ -0,0 +1,74 @@
+package word2vec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteBinary writes the model to w in the binary word2vec format, which can be read
+// using FromReader (or the original word2vec tools).  Words are written in the order
+// they were read.  Note that vectors are written normalised unless the model was
+// loaded with RawVectors.  Returns an error (before writing anything) if a word
+// contains a space or a newline, which the binary format cannot represent; such models
+// can be written with WriteText.
+func (m *Model) WriteBinary(w io.Writer) error {
+	for i := 0; i < m.Size(); i++ {
+		if word := m.vocab.word(i); strings.ContainsAny(word, " \n") {
+			return fmt.Errorf("word %q cannot be written in the binary format: contains a space or newline", word)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "%d %d\n", m.Size(), m.dim); err != nil {
+		return err
+	}
+
+	for i := 0; i < m.Size(); i++ {
+		if _, err := bw.WriteString(m.vocab.word(i)); err != nil {
+			return err
+		}
+		if err := bw.WriteByte(' '); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, binary.LittleEndian, m.row(i)); err != nil {
+			return err
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteText writes the model to w in the text word2vec format, which can be read using
+// FromTextReader.  Values are written with the minimum precision required to read them
+// back exactly.  As with WriteBinary, vectors are written normalised unless the model
+// was loaded with RawVectors.
+func (m *Model) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "%d %d\n", m.Size(), m.dim); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, 16)
+	for i := 0; i < m.Size(); i++ {
+		if _, err := bw.WriteString(m.vocab.word(i)); err != nil {
+			return err
+		}
+		for _, x := range m.row(i) {
+			buf = append(buf[:0], ' ')
+			buf = strconv.AppendFloat(buf, float64(x), 'g', -1, 32)
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
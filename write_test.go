@@ -0,0 +1,118 @@
+package word2vec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testModelData(t testing.TB, words []string, vecs []Vector) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, len(words), len(vecs[0]))
+	for i, w := range words {
+		fmt.Fprintf(buf, "%s ", w)
+		if err := binary.Write(buf, binary.LittleEndian, vecs[i]); err != nil {
+			t.Fatalf("unexpected error writing vector: %v", err)
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+	return buf.Bytes()
+}
+
+// testWord returns a distinct three-letter word for each i less than 26^3.
+func testWord(i int) string {
+	return string(rune('a'+i%26)) + string(rune('a'+i/26%26)) + string(rune('a'+i/676))
+}
+
+// testRandomModelData returns model data of size words (see testWord) with random
+// Gaussian vectors of dimension dim.  Each vector is then passed to the transforms in
+// turn, with the random source, so that they can modify it.
+func testRandomModelData(t testing.TB, size, dim int, seed int64, transforms ...func(r *rand.Rand, v Vector)) []byte {
+	r := rand.New(rand.NewSource(seed))
+	words := make([]string, size)
+	vecs := make([]Vector, size)
+	for i := range words {
+		words[i] = testWord(i)
+		vecs[i] = make(Vector, dim)
+		for j := range vecs[i] {
+			vecs[i][j] = float32(r.NormFloat64())
+		}
+		for _, f := range transforms {
+			f(r, vecs[i])
+		}
+	}
+	return testModelData(t, words, vecs)
+}
+
+func TestWriteBinary(t *testing.T) {
+	data := testModelData(t, []string{"hello", "world"}, []Vector{{0.6, 0.8}, {1, 0}})
+
+	m, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := m.WriteBinary(buf); err != nil {
+		t.Fatalf("unexpected error from WriteBinary: %v", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("WriteBinary() = %q, expected %q", buf.Bytes(), data)
+	}
+}
+
+func TestWriteBinarySpace(t *testing.T) {
+	m, err := FromTextReader(strings.NewReader("2 2\nnew york 0.6 0.8\nworld 1 0\n"))
+	if err != nil {
+		t.Fatalf("unexpected error from FromTextReader: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := m.WriteBinary(buf); err == nil {
+		t.Errorf("WriteBinary() with word containing a space returned nil error")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("WriteBinary() with word containing a space wrote %q, expected nothing", buf.Bytes())
+	}
+
+	if err := m.WriteText(buf); err != nil {
+		t.Fatalf("unexpected error from WriteText: %v", err)
+	}
+	m2, err := FromTextReader(buf)
+	if err != nil {
+		t.Fatalf("unexpected error from FromTextReader: %v", err)
+	}
+	words := []string{"new york", "world"}
+	if !reflect.DeepEqual(m.Map(words), m2.Map(words)) {
+		t.Errorf("m2.Map() = %v, expected %v", m2.Map(words), m.Map(words))
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	data := testModelData(t, []string{"hello", "world"}, []Vector{{0.6, 0.8}, {1, 0}})
+
+	m, err := FromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error from FromReader: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := m.WriteText(buf); err != nil {
+		t.Fatalf("unexpected error from WriteText: %v", err)
+	}
+
+	m2, err := FromTextReader(buf)
+	if err != nil {
+		t.Fatalf("unexpected error from FromTextReader: %v", err)
+	}
+
+	words := []string{"hello", "world"}
+	if !reflect.DeepEqual(m.Map(words), m2.Map(words)) {
+		t.Errorf("m2.Map() = %v, expected %v", m2.Map(words), m.Map(words))
+	}
+}